@@ -1,9 +1,13 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
 	"image/color"
 	"log"
+	"os"
+	"os/signal"
 	"time"
 
 	"tetris/lib"
@@ -13,7 +17,22 @@ import (
 func main() {
 	debug := flag.Bool("debug", false, "Disable timer and allow free movement")
 	level := flag.Int("level", 1, "Starting level (1-20)")
-	x := flag.Int("x", 550, "X resolution")
+	rotation := flag.String("rotation", "srs", "Rotation system (srs, ars, nes, classic)")
+	scoring := flag.String("scoring", "guideline", "Scoring (guideline, classic)")
+	randomizer := flag.String("randomizer", "7bag", "Randomizer (7bag, 14bag, pure, tgm, tgm2, nes)")
+	ruleset := flag.String("ruleset", "guideline", "Spawning and top out rules (guideline, classic), or a definition file of pieces and rules")
+	nes := flag.Bool("nes", false, "Play like the NES, ignoring rotation, scoring, randomizer and ruleset")
+	preview := flag.Int("preview", 3, "Number of upcoming pieces to show (1-7)")
+	width := flag.Int("width", lib.BOARD_WIDTH, "Number of columns on the board")
+	height := flag.Int("height", lib.GAMEOVER_LINE, "Number of visible rows on the board")
+	entryDelay := flag.Duration("are", 0, "Delay before the next piece comes in (ARE)")
+	clearDelay := flag.Duration("clear-delay", 0, "Delay before full lines are cleared")
+	das := flag.Duration("das", lib.DEFAULT_DAS, "Delay before a held direction repeats")
+	arr := flag.Duration("arr", lib.DEFAULT_ARR, "Delay between repeats of a held direction, 0 for instant")
+	sdf := flag.Int("sdf", lib.DEFAULT_SOFT_DROP_FACTOR, "How many times faster than gravity soft drops are")
+	save := flag.String("save", "", "Resume the game saved in this file, and save it there when quitting")
+	snapshots := flag.String("snapshots", "", "Write snapshots to this file or named pipe as lines of JSON, skipping them if it falls behind")
+	x := flag.Int("x", 800, "X resolution")
 	y := flag.Int("y", 1000, "Y resolution")
 	flag.Parse()
 
@@ -21,9 +40,77 @@ func main() {
 		log.Print("Debugging enabled")
 	}
 
-	evtMgr, disMgr := sdl.Init(*x, *y, *debug)
+	rs, ok := lib.RotationSystems[*rotation]
+	if !ok {
+		log.Fatalf("Unknown rotation system: %v", *rotation)
+	}
+
+	newScorer, ok := lib.Scorers[*scoring]
+	if !ok {
+		log.Fatalf("Unknown scoring: %v", *scoring)
+	}
+
+	newRandomizer, ok := lib.Randomizers[*randomizer]
+	if !ok {
+		log.Fatalf("Unknown randomizer: %v", *randomizer)
+	}
+
+	// Anything that isn't the name of a ruleset is a definition file
+	rules, ok := lib.Rulesets[*ruleset]
+	var def *lib.Definition
+	if !ok {
+		var err error
+		def, err = lib.LoadDefinitionFile(*ruleset)
+		if err != nil {
+			log.Fatalf("Unknown ruleset: %v", err)
+		}
+	}
+
+	input := lib.InputConfig{DAS: *das, ARR: *arr, SoftDropFactor: *sdf}
+	evtMgr, disMgr := sdl.Init(*x, *y, *debug, input)
+	defer sdl.Quit()
+
+	opts := []lib.GameOption{
+		lib.WithRotationSystem(rs),
+		lib.WithScorer(newScorer()),
+		lib.WithRandomizer(newRandomizer),
+		lib.WithRuleset(rules),
+		lib.WithPreview(*preview),
+		lib.WithBoardSize(*width, *height),
+		lib.WithHeldButtons(evtMgr.Held),
+	}
+	// A definition file brings it's own pieces, rotations and rules
+	if def != nil {
+		opts = append(opts, def.Options()...)
+	}
+	if *nes {
+		opts = append(opts, lib.WithNES())
+	}
+	// NES mode has it's own delays, only replace them if asked to
+	if *entryDelay > 0 {
+		opts = append(opts, lib.WithEntryDelay(*entryDelay))
+	}
+	if *clearDelay > 0 {
+		opts = append(opts, lib.WithLineClearDelay(*clearDelay))
+	}
+
+	game := lib.NewGame(time.Now().UnixNano(), *level, opts...)
+
+	// The other flags have to be the same as when the game was saved,
+	// or the save is refused
+	if *save != "" {
+		data, err := os.ReadFile(*save)
+		switch {
+		case err == nil:
+			if err := game.UnmarshalBinary(data); err != nil {
+				log.Fatalf("Can't resume %v: %v", *save, err)
+			}
+			log.Printf("Resumed game from %v", *save)
+		case !os.IsNotExist(err):
+			log.Fatalf("Can't resume %v: %v", *save, err)
+		}
+	}
 
-	game := lib.NewGame(time.Now().UnixNano(), *level)
 	initState := game.Snap()
 
 	palette := [7]color.RGBA{
@@ -36,14 +123,124 @@ func main() {
 		color.RGBA{214, 57, 60, 255},
 	}
 
-	boardComp := sdl.NewBoardComponent(initState.Board, palette, *x, *y)
+	boardComp := sdl.NewBoardComponent(initState.View(), palette, *x, *y)
+	previewComp := sdl.NewPreviewComponent(initState, palette, *x, *y)
 
 	disMgr.Add(boardComp)
-	disMgr.AddSurf(sdl.MakeGrid(*x, *y))
+	disMgr.AddSurf(sdl.MakeGrid(*x, *y, initState.Board.Width(), initState.Board.Visible()))
+	disMgr.Add(previewComp)
 
 	snaps := make(chan lib.GameSnapshot)
 
-	go disMgr.Render(snaps)
+	// Overlays and other tools can follow along with the game by
+	// reading the snapshots. They're written off to the side, so a
+	// slow reader only misses some of them instead of holding up the
+	// game
+	var snapOut chan lib.GameSnapshot
+	written := make(chan struct{})
+	if *snapshots != "" {
+		snapOut = make(chan lib.GameSnapshot, 64)
+		go writeSnapshots(*snapshots, snapOut, written)
+	} else {
+		close(written)
+	}
+
+	// Keep the input handler up to date with the gravity, so soft
+	// drops stay faster than it as the game speeds up, and with which
+	// tetromino is in play
+	renderSnaps := make(chan lib.GameSnapshot)
+	go func() {
+		piece := initState.Piece
+		for snap := range snaps {
+			evtMgr.SetGravity(snap.Gravity)
+			if snap.Piece != piece {
+				evtMgr.NewPiece()
+				piece = snap.Piece
+			}
+			if snapOut != nil {
+				select {
+				case snapOut <- snap:
+				default:
+				}
+			}
+			renderSnaps <- snap
+		}
+		close(renderSnaps)
+		if snapOut != nil {
+			close(snapOut)
+		}
+	}()
+
+	rendered := make(chan struct{})
+	go func() {
+		disMgr.Render(renderSnaps)
+		close(rendered)
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// Closing the window stops the game the same way, so it's saved
+	go func() {
+		select {
+		case <-evtMgr.Quit:
+			stop()
+		case <-ctx.Done():
+		}
+	}()
+
+	result := game.Play(ctx, evtMgr.C, snaps, *debug)
+	evtMgr.Stop()
+	<-rendered
+
+	// Give the last snapshots a moment to be written, without waiting
+	// on a reader that's gone away
+	select {
+	case <-written:
+	case <-time.After(time.Second):
+	}
+
+	log.Printf("Game %v. Score: %v, lines: %v, level: %v",
+		result.Reason, result.Score, result.Lines, result.Level)
 
-	game.Play(evtMgr.C, snaps, *debug)
+	// Games that are over start fresh next time
+	if *save != "" {
+		if game.IsGameover() {
+			if err := os.Remove(*save); err != nil && !os.IsNotExist(err) {
+				log.Printf("Can't remove %v: %v", *save, err)
+			}
+			return
+		}
+
+		data, err := game.MarshalBinary()
+		if err == nil {
+			err = os.WriteFile(*save, data, 0644)
+		}
+		if err != nil {
+			log.Fatalf("Can't save to %v: %v", *save, err)
+		}
+		log.Printf("Saved game to %v", *save)
+	}
+}
+
+// Writes snapshots as lines of JSON until the channel is closed, then
+// closes done. Opening a named pipe waits for something to read it, so
+// this runs on it's own.
+func writeSnapshots(path string, snaps <-chan lib.GameSnapshot, done chan<- struct{}) {
+	defer close(done)
+
+	f, err := os.Create(path)
+	if err != nil {
+		log.Printf("Can't write snapshots: %v", err)
+		return
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	for snap := range snaps {
+		if err := enc.Encode(snap); err != nil {
+			log.Printf("Can't write snapshot: %v", err)
+			return
+		}
+	}
 }
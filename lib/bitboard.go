@@ -0,0 +1,275 @@
+package lib
+
+// The widest a BitBoard can be, since each row is a single uint32
+const MAX_BITBOARD_WIDTH = 32
+
+// A BitBoard holds the same tiles as a Board, but keeps each row as a
+// bitmask of which tiles are filled, with the colors alongside in a
+// plane of their own. Checking for full rows and collisions only looks
+// at the bitmasks, and clearing lines moves whole rows at a time. It's
+// meant for bots that try out huge numbers of placements. The colors
+// are kept bottom row first, a row at a time.
+type BitBoard struct {
+	width   int
+	height  int
+	visible int
+	// A row with every tile filled
+	full   uint32
+	rows   []uint32
+	colors []TileColor
+}
+
+// Creates an empty BitBoard that the player sees width by visible
+// tiles of, with as many rows again hidden above them just like a
+// Board. Widths are kept between MIN_BOARD_WIDTH and
+// MAX_BITBOARD_WIDTH, and there's at least MIN_BOARD_VISIBLE rows.
+func NewBitBoard(width, visible int) *BitBoard {
+	if width < MIN_BOARD_WIDTH {
+		width = MIN_BOARD_WIDTH
+	} else if width > MAX_BITBOARD_WIDTH {
+		width = MAX_BITBOARD_WIDTH
+	}
+	if visible < MIN_BOARD_VISIBLE {
+		visible = MIN_BOARD_VISIBLE
+	}
+
+	height := visible * 2
+	return &BitBoard{
+		width:   width,
+		height:  height,
+		visible: visible,
+		full:    uint32(1<<width - 1),
+		rows:    make([]uint32, height),
+		colors:  make([]TileColor, width*height),
+	}
+}
+
+// Creates a BitBoard with the same size and tiles as a Board. Boards
+// wider than MAX_BITBOARD_WIDTH lose the columns past it.
+func NewBitBoardFrom(board *Board) *BitBoard {
+	bb := NewBitBoard(board.Width(), board.Visible())
+	for y := 0; y < bb.height; y++ {
+		for x := 0; x < bb.width; x++ {
+			bb.SetTile(board.GetTile(x, y), x, y)
+		}
+	}
+
+	return bb
+}
+
+// Returns a Board with the same size and tiles
+func (bb *BitBoard) Board() *Board {
+	board := NewBoard(bb.width, bb.visible)
+	for y := 0; y < bb.height; y++ {
+		for x := 0; x < bb.width; x++ {
+			if t := bb.GetTile(x, y); t != EMPTY {
+				board.SetTile(t, x, y)
+			}
+		}
+	}
+
+	return board
+}
+
+func (bb *BitBoard) Width() int {
+	return bb.width
+}
+
+func (bb *BitBoard) Height() int {
+	return bb.height
+}
+
+func (bb *BitBoard) Visible() int {
+	return bb.visible
+}
+
+// Returns true if the coordinates are on the board
+func (bb *BitBoard) InBounds(x, y int) bool {
+	return x >= 0 && x < bb.width && y >= 0 && y < bb.height
+}
+
+// Returns the color of a tile. The bottom left point is 0,0. Panics
+// if it's not on the board, see TryGetTile.
+func (bb *BitBoard) GetTile(x, y int) TileColor {
+	t, err := bb.TryGetTile(x, y)
+	if err != nil {
+		panic(err)
+	}
+
+	return t
+}
+
+// Like GetTile, but returns ErrOutOfBounds instead of panicking
+func (bb *BitBoard) TryGetTile(x, y int) (TileColor, error) {
+	if !bb.InBounds(x, y) {
+		return EMPTY, outOfBounds(x, y)
+	}
+
+	return bb.colors[y*bb.width+x], nil
+}
+
+// Panics if the tile is invalid or not on the board, see TrySetTile
+func (bb *BitBoard) SetTile(t TileColor, x, y int) {
+	if err := bb.TrySetTile(t, x, y); err != nil {
+		panic(err)
+	}
+}
+
+// Like SetTile, but returns ErrInvalidTile or ErrOutOfBounds instead
+// of panicking. The board is left alone if there's an error.
+func (bb *BitBoard) TrySetTile(t TileColor, x, y int) error {
+	if err := checkTile(t); err != nil {
+		return err
+	}
+	if !bb.InBounds(x, y) {
+		return outOfBounds(x, y)
+	}
+
+	bb.colors[y*bb.width+x] = t
+	if t == EMPTY {
+		bb.rows[y] &^= 1 << x
+	} else {
+		bb.rows[y] |= 1 << x
+	}
+	return nil
+}
+
+// Panics if the tile isn't on the board, just like GetTile
+func (bb *BitBoard) IsEmpty(x, y int) bool {
+	if !bb.InBounds(x, y) {
+		panic(outOfBounds(x, y))
+	}
+
+	return bb.rows[y]&(1<<x) == 0
+}
+
+// Returns true if a tile can't be moved into, either because it's
+// outside of the board or because there's something there already
+func (bb *BitBoard) Blocked(x, y int) bool {
+	return !bb.InBounds(x, y) || !bb.IsEmpty(x, y)
+}
+
+// Returns true if every tile in a row is filled. Panics if the row
+// isn't on the board, just like IsEmpty
+func (bb *BitBoard) IsFull(y int) bool {
+	if !bb.InBounds(0, y) {
+		panic(outOfBounds(0, y))
+	}
+
+	return bb.rows[y] == bb.full
+}
+
+// Returns the rows of a tetromino's mask as bitmasks for the board,
+// top row first. Returns false if any of it is off the sides.
+func (bb *BitBoard) tetRows(tet ActiveTetromino, rows []uint32) ([]uint32, bool) {
+	mask := *tet.mask
+	for dy := 0; dy < tet.size; dy++ {
+		var row uint32
+		for dx := 0; dx < tet.size; dx++ {
+			if !mask[dy*tet.size+dx] {
+				continue
+			}
+
+			x := tet.x + dx
+			if x < 0 || x >= bb.width {
+				return rows, false
+			}
+			row |= 1 << x
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, true
+}
+
+// Returns true if the tetromino is within the board and doesn't
+// overlap any tiles, the same as it fitting on a Board. Each row is
+// checked with a single AND.
+func (bb *BitBoard) Fits(tet ActiveTetromino) bool {
+	var buf [MAX_BITBOARD_WIDTH]uint32
+	rows, ok := bb.tetRows(tet, buf[:0])
+	if !ok {
+		return false
+	}
+
+	for dy, row := range rows {
+		if row == 0 {
+			continue
+		}
+
+		y := tet.y - dy
+		if y < 0 || y >= bb.height || bb.rows[y]&row != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Draws the tetromino's tiles onto the board in it's color. It has to
+// fit, see Fits.
+func (bb *BitBoard) Stamp(tet ActiveTetromino) {
+	for _, p := range tet.ListPositions() {
+		bb.SetTile(ShapeToTC(tet.shape), p.x, p.y)
+	}
+}
+
+// Returns the full rows, from the bottom up
+func (bb *BitBoard) FullLines() []int {
+	lines := []int{}
+	for y, row := range bb.rows {
+		if row == bb.full {
+			lines = append(lines, y)
+		}
+	}
+
+	return lines
+}
+
+// Clears all full lines, and moves the rows above them down to fill
+// the gaps. Returns the number of lines cleared.
+func (bb *BitBoard) Tetris() int {
+	// Every row that isn't full is moved down over the full ones below
+	// it, a row at a time
+	var to int
+	for from, row := range bb.rows {
+		if row == bb.full {
+			continue
+		}
+
+		if to != from {
+			bb.rows[to] = row
+			copy(bb.colors[to*bb.width:(to+1)*bb.width], bb.colors[from*bb.width:(from+1)*bb.width])
+		}
+		to++
+	}
+
+	// Then whatever is left at the top is emptied out
+	cleared := bb.height - to
+	for y := to; y < bb.height; y++ {
+		bb.rows[y] = 0
+	}
+	for i := to * bb.width; i < len(bb.colors); i++ {
+		bb.colors[i] = EMPTY
+	}
+
+	return cleared
+}
+
+// Empties out the whole board
+func (bb *BitBoard) Clear() {
+	for i := range bb.rows {
+		bb.rows[i] = 0
+	}
+	for i := range bb.colors {
+		bb.colors[i] = EMPTY
+	}
+}
+
+// Returns a copy of the board that has tiles of it's own
+func (bb *BitBoard) Clone() *BitBoard {
+	clone := *bb
+	clone.rows = append([]uint32(nil), bb.rows...)
+	clone.colors = append([]TileColor(nil), bb.colors...)
+	return &clone
+}
@@ -0,0 +1,241 @@
+package lib
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+// Fills a Board and a BitBoard with the same random tiles, with some
+// full rows mixed in
+func randomBoards(r *rand.Rand) (*Board, *BitBoard) {
+	board := &Board{}
+	bb := NewBitBoard(BOARD_WIDTH, GAMEOVER_LINE)
+	tiles := []TileColor{EMPTY, C1, C2, C3, C4, C5, C6, C7}
+
+	for y := 0; y < GAMEOVER_LINE; y++ {
+		full := r.Intn(3) == 0
+		for x := 0; x < BOARD_WIDTH; x++ {
+			tile := tiles[r.Intn(len(tiles))]
+			if full && tile == EMPTY {
+				tile = C1
+			}
+
+			board.SetTile(tile, x, y)
+			bb.SetTile(tile, x, y)
+		}
+	}
+
+	return board, bb
+}
+
+func sameTiles(t *testing.T, board *Board, bb *BitBoard) {
+	t.Helper()
+
+	for y := 0; y < BOARD_HEIGHT; y++ {
+		for x := 0; x < BOARD_WIDTH; x++ {
+			if board.GetTile(x, y) != bb.GetTile(x, y) || board.IsEmpty(x, y) != bb.IsEmpty(x, y) {
+				t.Fatalf("Boards differ at (%v, %v): %v and %v", x, y, board.GetTile(x, y), bb.GetTile(x, y))
+			}
+		}
+	}
+}
+
+func TestBitBoardMatchesBoard(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 50; i++ {
+		board, bb := randomBoards(r)
+		sameTiles(t, board, bb)
+
+		full := board.FullLines()
+		found := bb.FullLines()
+		if len(full) != len(found) {
+			t.Fatalf("Expected full lines %v, found %v", full, found)
+		}
+		for j := range full {
+			if full[j] != found[j] || !bb.IsFull(found[j]) {
+				t.Fatalf("Expected full lines %v, found %v", full, found)
+			}
+		}
+
+		if cleared := bb.Tetris(); cleared != board.Tetris() {
+			t.Fatalf("Expected %v lines cleared, found %v", len(full), cleared)
+		}
+		sameTiles(t, board, bb)
+	}
+}
+
+func TestBitBoardConvert(t *testing.T) {
+	board, _ := randomBoards(rand.New(rand.NewSource(2)))
+
+	bb := NewBitBoardFrom(board)
+	sameTiles(t, board, bb)
+	if !bb.Board().Equal(board) {
+		t.Error("Expected converting back to give the same board")
+	}
+
+	// Odd sizes carry over too
+	small := NewBoard(6, 8)
+	if bb := NewBitBoardFrom(small); bb.Width() != 6 || bb.Visible() != 8 || bb.Height() != 16 {
+		t.Errorf("Expected a 6x8 board, found %vx%v", bb.Width(), bb.Visible())
+	}
+}
+
+func TestBitBoardFits(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	board, bb := randomBoards(r)
+
+	for _, shape := range []Shape{TET_SQUARE, TET_S, TET_Z, TET_L, TET_T, TET_J, TET_LINE} {
+		tet := NewTet(shape)
+		for turn := 0; turn < 4; turn++ {
+			for y := -2; y < BOARD_HEIGHT+2; y++ {
+				for x := -4; x < BOARD_WIDTH+2; x++ {
+					at := ActiveTetromino{tet, Position{x, y}}
+					if at.fits(board) != bb.Fits(at) {
+						t.Fatalf("Shape %v at (%v, %v): expected fits to be %v", shape, x, y, at.fits(board))
+					}
+				}
+			}
+			tet.RotLeft()
+		}
+	}
+}
+
+func TestBitBoardStamp(t *testing.T) {
+	bb := NewBitBoard(BOARD_WIDTH, GAMEOVER_LINE)
+	tet := ActiveTetromino{NewTet(TET_T), Position{3, 5}}
+
+	bb.Stamp(tet)
+	for _, p := range tet.ListPositions() {
+		if bb.GetTile(p.x, p.y) != ShapeToTC(TET_T) {
+			t.Errorf("Expected a tile at %v", p)
+		}
+	}
+	if bb.Fits(tet) {
+		t.Error("Expected the tetromino not to fit over itself")
+	}
+
+	clone := bb.Clone()
+	bb.Clear()
+	if !bb.IsEmpty(tet.ListPositions()[0].x, tet.ListPositions()[0].y) {
+		t.Error("Expected the board to be cleared")
+	}
+	if clone.Fits(tet) {
+		t.Error("Clone shares it's tiles with the original board")
+	}
+}
+
+func TestNewBitBoardLimits(t *testing.T) {
+	if bb := NewBitBoard(1, 1); bb.Width() != MIN_BOARD_WIDTH || bb.Visible() != MIN_BOARD_VISIBLE {
+		t.Errorf("Expected the board to be raised to the minimum size, found %vx%v", bb.Width(), bb.Visible())
+	}
+
+	bb := NewBitBoard(64, 20)
+	if bb.Width() != MAX_BITBOARD_WIDTH {
+		t.Fatalf("Expected the board to be cut to %v wide, found %v", MAX_BITBOARD_WIDTH, bb.Width())
+	}
+	for x := 0; x < MAX_BITBOARD_WIDTH; x++ {
+		bb.SetTile(C1, x, 0)
+	}
+	if !bb.IsFull(0) || bb.Tetris() != 1 {
+		t.Error("Expected the widest row to be cleared")
+	}
+}
+
+func TestBitBoardBounds(t *testing.T) {
+	bb := NewBitBoard(10, 20)
+
+	// Past the edge of a narrow board, past the edge of a row's bits,
+	// and off the top
+	for _, p := range []Position{{10, 0}, {40, 0}, {-1, 0}, {0, 40}} {
+		func() {
+			defer func() {
+				if err, ok := recover().(error); !ok || !errors.Is(err, ErrOutOfBounds) {
+					t.Errorf("%v: expected to panic with ErrOutOfBounds, found %v", p, err)
+				}
+			}()
+			bb.IsEmpty(p.x, p.y)
+		}()
+	}
+
+	// Rows below and above the board
+	for _, y := range []int{-1, bb.Height()} {
+		func() {
+			defer func() {
+				if err, ok := recover().(error); !ok || !errors.Is(err, ErrOutOfBounds) {
+					t.Errorf("Row %v: expected IsFull to panic with ErrOutOfBounds, found %v", y, err)
+				}
+			}()
+			bb.IsFull(y)
+		}()
+	}
+}
+
+// The boards the benchmarks work on. About a third of the visible rows
+// are full
+func benchBoards() (*Board, *BitBoard) {
+	return randomBoards(rand.New(rand.NewSource(4)))
+}
+
+func BenchmarkBoardFullLines(b *testing.B) {
+	board, _ := benchBoards()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		board.FullLines()
+	}
+}
+
+func BenchmarkBitBoardFullLines(b *testing.B) {
+	_, bb := benchBoards()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bb.FullLines()
+	}
+}
+
+func BenchmarkBoardTetris(b *testing.B) {
+	board, _ := benchBoards()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		clone := board.Clone()
+		clone.Tetris()
+	}
+}
+
+func BenchmarkBitBoardTetris(b *testing.B) {
+	_, bb := benchBoards()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		clone := bb.Clone()
+		clone.Tetris()
+	}
+}
+
+// Checks every position a T could be dropped in on the board, the way
+// a bot looking for a placement would
+func BenchmarkBoardFits(b *testing.B) {
+	board, _ := benchBoards()
+	tet := ActiveTetromino{NewTet(TET_T), Position{}}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for tet.y = 0; tet.y < BOARD_HEIGHT; tet.y++ {
+			for tet.x = -1; tet.x < BOARD_WIDTH; tet.x++ {
+				tet.fits(board)
+			}
+		}
+	}
+}
+
+func BenchmarkBitBoardFits(b *testing.B) {
+	_, bb := benchBoards()
+	tet := ActiveTetromino{NewTet(TET_T), Position{}}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for tet.y = 0; tet.y < BOARD_HEIGHT; tet.y++ {
+			for tet.x = -1; tet.x < BOARD_WIDTH; tet.x++ {
+				bb.Fits(tet)
+			}
+		}
+	}
+}
@@ -5,12 +5,18 @@ import (
 	"strings"
 )
 
+// The size of a board unless it's made otherwise. The player sees the
+// bottom GAMEOVER_LINE rows of it
 const (
 	BOARD_WIDTH  = 10
 	BOARD_HEIGHT = 40
 	BOARD_SIZE   = BOARD_WIDTH * BOARD_HEIGHT
 )
 
+// The fewest columns and visible rows a board can be made with
+const MIN_BOARD_WIDTH = 4
+const MIN_BOARD_VISIBLE = 4
+
 type TileColor int
 
 // A board is basically a grid of tiles that have an associated
@@ -29,46 +35,193 @@ const (
 	C7
 )
 
+// Only the bottom rows of a board are visible, the same number of rows
+// again are hidden above them for tetrominos to come in through. The
+// zero value is an empty board of the default size, ready to use.
+// Boards share their tiles when they're copied, use Clone to get one
+// that doesn't.
 type Board struct {
-	tiles [BOARD_SIZE]TileColor
+	width   int
+	height  int
+	visible int
+	tiles   []TileColor
+}
+
+// Creates an empty board that the player sees width by visible tiles
+// of. Sizes under MIN_BOARD_WIDTH and MIN_BOARD_VISIBLE are raised to
+// them.
+func NewBoard(width, visible int) *Board {
+	if width < MIN_BOARD_WIDTH {
+		width = MIN_BOARD_WIDTH
+	}
+	if visible < MIN_BOARD_VISIBLE {
+		visible = MIN_BOARD_VISIBLE
+	}
+
+	return &Board{
+		width:   width,
+		height:  visible * 2,
+		visible: visible,
+		tiles:   make([]TileColor, width*visible*2),
+	}
+}
+
+// Sets up the tiles of a zero value board. It's not done until
+// something is actually changed, so empty boards are cheap
+func (b *Board) init() {
+	if b.tiles == nil {
+		*b = *NewBoard(b.Width(), b.Visible())
+	}
+}
+
+// Returns the number of columns on the board
+func (b *Board) Width() int {
+	if b.width == 0 {
+		return BOARD_WIDTH
+	}
+	return b.width
+}
+
+// Returns the number of rows on the board, including the hidden ones
+func (b *Board) Height() int {
+	if b.height == 0 {
+		return BOARD_HEIGHT
+	}
+	return b.height
+}
+
+// Returns the number of rows the player can see, from the bottom
+func (b *Board) Visible() int {
+	if b.visible == 0 {
+		return GAMEOVER_LINE
+	}
+	return b.visible
+}
+
+// Returns true if the coordinates are on the board
+func (b *Board) InBounds(x, y int) bool {
+	return x >= 0 && x < b.Width() && y >= 0 && y < b.Height()
+}
+
+// Returns true if a tile can't be moved into, either because it's
+// outside of the board or because there's something there already
+func (b *Board) Blocked(x, y int) bool {
+	return !b.InBounds(x, y) || !b.IsEmpty(x, y)
 }
 
 // GetTile returns the index of the provided tile. The bottom left
-// point is considered 0,0
+// point is considered 0,0. Panics if it's not on the board, see
+// TryGetTile.
 func (b *Board) GetTile(x, y int) TileColor {
-	if x < 0 || x >= BOARD_WIDTH {
-		panic("Tile outside of board width")
+	t, err := b.TryGetTile(x, y)
+	if err != nil {
+		panic(err)
 	}
-	if y < 0 || y >= BOARD_HEIGHT {
-		panic("Tile outside of board height")
+
+	return t
+}
+
+// Like GetTile, but returns ErrOutOfBounds instead of panicking
+func (b *Board) TryGetTile(x, y int) (TileColor, error) {
+	if !b.InBounds(x, y) {
+		return EMPTY, outOfBounds(x, y)
 	}
 
-	return b.tiles[coordToTileIdx(x, y)]
+	if b.tiles == nil {
+		return EMPTY, nil
+	}
+
+	return b.tiles[b.coordToTileIdx(x, y)], nil
+}
+
+func outOfBounds(x, y int) error {
+	return fmt.Errorf("%w: tile (%v, %v) isn't on the board", ErrOutOfBounds, x, y)
 }
 
 // Helper function which converts coordinates for us
-func coordToTileIdx(x, y int) int {
-	y = BOARD_HEIGHT - y - 1
-	return y*BOARD_WIDTH + x
+func (b *Board) coordToTileIdx(x, y int) int {
+	y = b.Height() - y - 1
+	return y*b.Width() + x
 }
 
 // A helper for testing that a given tile color is in the valid range
-// of values we've set
+// of values we've set. Every registered shape adds a color past C7.
 func invalidTile(t TileColor) bool {
-	return t > C7 || t < EMPTY
+	return t > ShapeToTC(Shape(len(grids)-1)) || t < EMPTY
 }
 
-func (b *Board) SetTile(t TileColor, x, y int) {
+func checkTile(t TileColor) error {
 	if invalidTile(t) {
-		panic("Invalid tile value passed")
+		return fmt.Errorf("%w: %v", ErrInvalidTile, t)
 	}
 
-	b.tiles[coordToTileIdx(x, y)] = t
+	return nil
+}
+
+// Panics if the tile is invalid or not on the board, see TrySetTile
+func (b *Board) SetTile(t TileColor, x, y int) {
+	if err := b.TrySetTile(t, x, y); err != nil {
+		panic(err)
+	}
+}
+
+// Like SetTile, but returns ErrInvalidTile or ErrOutOfBounds instead
+// of panicking. The board is left alone if there's an error.
+func (b *Board) TrySetTile(t TileColor, x, y int) error {
+	if err := checkTile(t); err != nil {
+		return err
+	}
+	if !b.InBounds(x, y) {
+		return outOfBounds(x, y)
+	}
+
+	b.init()
+	b.tiles[b.coordToTileIdx(x, y)] = t
+	return nil
 }
 
 // Clear completely resets the board with a new one that's empty
 func (b *Board) Clear() {
-	b.tiles = [BOARD_SIZE]TileColor{}
+	for i := range b.tiles {
+		b.tiles[i] = EMPTY
+	}
+}
+
+// Returns a copy of the board that has tiles of it's own
+func (b *Board) Clone() Board {
+	clone := *b
+	if b.tiles != nil {
+		clone.tiles = append([]TileColor(nil), b.tiles...)
+	}
+	return clone
+}
+
+// Returns true if both boards are the same size, and have the same
+// tiles in the same places
+func (b *Board) Equal(other *Board) bool {
+	if b.Width() != other.Width() || b.Height() != other.Height() || b.Visible() != other.Visible() {
+		return false
+	}
+
+	for y := 0; y < b.Height(); y++ {
+		for x := 0; x < b.Width(); x++ {
+			if b.GetTile(x, y) != other.GetTile(x, y) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// Returns true if there isn't a single tile on the board
+func (b *Board) IsClear() bool {
+	for _, t := range b.tiles {
+		if t != EMPTY {
+			return false
+		}
+	}
+	return true
 }
 
 // Tetris clears all full lines, and then shifts any tiles
@@ -81,20 +234,20 @@ func (b *Board) Tetris() int {
 		// BOTTOM.
 		y = lines[i]
 		// Erase the tiles in the line.
-		for x := 0; x < BOARD_WIDTH; x++ {
+		for x := 0; x < b.Width(); x++ {
 			b.SetTile(EMPTY, x, y)
 		}
 
 		// Shift every tile above the line down by 1
-		for n := y; n < BOARD_HEIGHT-1; n++ {
-			for x := 0; x < BOARD_WIDTH; x++ {
+		for n := y; n < b.Height()-1; n++ {
+			for x := 0; x < b.Width(); x++ {
 				b.SetTile(b.GetTile(x, n+1), x, n)
 			}
 		}
 
 		// Finally erase the top line, since it should now be empty
-		for x := 0; x < BOARD_WIDTH; x++ {
-			b.SetTile(EMPTY, x, BOARD_HEIGHT-1)
+		for x := 0; x < b.Width(); x++ {
+			b.SetTile(EMPTY, x, b.Height()-1)
 		}
 	}
 
@@ -104,9 +257,9 @@ func (b *Board) Tetris() int {
 func (b *Board) FullLines() []int {
 	lines := []int{}
 
-	for y := 0; y < BOARD_HEIGHT; y++ {
+	for y := 0; y < b.Height(); y++ {
 		var isEmpty bool
-		for x := 0; x < BOARD_WIDTH; x++ {
+		for x := 0; x < b.Width(); x++ {
 			if b.IsEmpty(x, y) {
 				isEmpty = true
 			}
@@ -127,9 +280,14 @@ func (b *Board) IsEmpty(x, y int) bool {
 // Draw a literal grid that then contains the number representing the
 // tile. If it's empty, leave it blank as a space.
 func (b *Board) String() string {
-	topLine := "┌─┬─┬─┬─┬─┬─┬─┬─┬─┬─┐"
-	midLine := "├─┼─┼─┼─┼─┼─┼─┼─┼─┼─┤"
-	botLine := "└─┴─┴─┴─┴─┴─┴─┴─┴─┴─┘"
+	// Builds a line across the board out of the given pieces
+	line := func(left, mid, right string) string {
+		return left + strings.Repeat("─"+mid, b.Width()-1) + "─" + right
+	}
+
+	topLine := line("┌", "┬", "┐")
+	midLine := line("├", "┼", "┤")
+	botLine := line("└", "┴", "┘")
 
 	builder := &strings.Builder{}
 	builder.WriteRune('\n')
@@ -140,20 +298,25 @@ func (b *Board) String() string {
 	var tileStr string
 	var lineBuilder *strings.Builder
 
-	// Start several lines down, since the top portion of the board is
-	// really obscured
-	for i := 16; i < BOARD_HEIGHT; i++ {
+	// Start a few lines above the visible ones, since the top portion
+	// of the board is really obscured
+	top := b.Visible() + 3
+	if top >= b.Height() {
+		top = b.Height() - 1
+	}
+
+	for y := top; y >= 0; y-- {
 
 		lineBuilder = &strings.Builder{}
 		lineBuilder.WriteRune('│')
 
-		for j := 0; j < BOARD_WIDTH; j++ {
+		for x := 0; x < b.Width(); x++ {
 
-			tile = b.tiles[i*BOARD_WIDTH+j]
+			tile = b.GetTile(x, y)
 
 			if tile != EMPTY {
 				tileStr = fmt.Sprintf("%v", tile)
-			} else if i == BOARD_HEIGHT-GAMEOVER_LINE-1 {
+			} else if y == b.Visible() {
 				tileStr = "▒"
 			} else {
 				tileStr = " "
@@ -165,7 +328,7 @@ func (b *Board) String() string {
 		builder.WriteRune('\n')
 
 		// Print all but last line
-		if i != BOARD_HEIGHT-1 {
+		if y != 0 {
 			builder.WriteString(midLine)
 			builder.WriteRune('\n')
 		}
@@ -1,7 +1,9 @@
 package lib
 
 import (
+	"errors"
 	"math/rand"
+	"strings"
 	"testing"
 )
 
@@ -155,3 +157,97 @@ func TestIsEmpty(t *testing.T) {
 		t.Error("Position is not empty after setting")
 	}
 }
+
+func TestBoardSizes(t *testing.T) {
+	for _, size := range [][2]int{{4, 20}, {12, 20}, {10, 40}} {
+		width, visible := size[0], size[1]
+		b := NewBoard(width, visible)
+
+		if b.Width() != width || b.Visible() != visible || b.Height() != visible*2 {
+			t.Errorf("Expected a %vx%v board, found %vx%v with %v rows",
+				width, visible, b.Width(), b.Visible(), b.Height())
+		}
+
+		// The corners are the last tiles on the board
+		b.SetTile(C1, width-1, b.Height()-1)
+		if !b.Blocked(width, 0) || !b.Blocked(0, b.Height()) || !b.Blocked(width-1, b.Height()-1) {
+			t.Errorf("%vx%v: expected the edges and filled tiles to be blocked", width, visible)
+		}
+
+		// Clearing a line works across the whole width
+		for x := 0; x < width; x++ {
+			b.SetTile(C2, x, 0)
+		}
+		if lines := b.Tetris(); lines != 1 {
+			t.Errorf("%vx%v: expected a line to be cleared, found %v", width, visible, lines)
+		}
+
+		// A line in the drawing for each visible row and a few more
+		if rows := strings.Count(b.String(), "│\n"); rows != visible+4 {
+			t.Errorf("%vx%v: expected %v rows to be drawn, found %v", width, visible, visible+4, rows)
+		}
+	}
+}
+
+func TestNewBoardMinimum(t *testing.T) {
+	b := NewBoard(1, 1)
+	if b.Width() != MIN_BOARD_WIDTH || b.Visible() != MIN_BOARD_VISIBLE {
+		t.Errorf("Expected the board to be raised to the minimum size, found %vx%v", b.Width(), b.Visible())
+	}
+}
+
+func TestBoardClone(t *testing.T) {
+	b := NewBoard(6, 10)
+	b.SetTile(C1, 1, 1)
+
+	clone := b.Clone()
+	if !clone.Equal(b) {
+		t.Fatal("Clone doesn't match the board it was made from")
+	}
+
+	// Changing one leaves the other alone
+	clone.SetTile(C2, 2, 2)
+	if !b.IsEmpty(2, 2) || clone.Equal(b) {
+		t.Error("Clone shares it's tiles with the original board")
+	}
+
+	// The zero value is the same as a default board
+	if !(&Board{}).Equal(NewBoard(BOARD_WIDTH, GAMEOVER_LINE)) {
+		t.Error("Expected the zero value to be an empty default board")
+	}
+}
+
+func TestBoardTryTile(t *testing.T) {
+	b := NewBoard(6, 10)
+
+	if _, err := b.TryGetTile(6, 0); !errors.Is(err, ErrOutOfBounds) {
+		t.Errorf("Expected ErrOutOfBounds past the right edge, found %v", err)
+	}
+	if _, err := b.TryGetTile(0, -1); !errors.Is(err, ErrOutOfBounds) {
+		t.Errorf("Expected ErrOutOfBounds below the board, found %v", err)
+	}
+	if err := b.TrySetTile(C1, 0, b.Height()); !errors.Is(err, ErrOutOfBounds) {
+		t.Errorf("Expected ErrOutOfBounds above the board, found %v", err)
+	}
+	if err := b.TrySetTile(-1, 0, 0); !errors.Is(err, ErrInvalidTile) {
+		t.Errorf("Expected ErrInvalidTile, found %v", err)
+	}
+	if !b.IsClear() {
+		t.Error("Expected the board to be left alone")
+	}
+
+	if err := b.TrySetTile(C2, 5, 19); err != nil {
+		t.Fatal(err)
+	}
+	if tile, err := b.TryGetTile(5, 19); err != nil || tile != C2 {
+		t.Errorf("Expected to get the tile back, found %v, %v", tile, err)
+	}
+
+	// The panicking versions panic with the same errors
+	defer func() {
+		if err, ok := recover().(error); !ok || !errors.Is(err, ErrOutOfBounds) {
+			t.Errorf("Expected to panic with ErrOutOfBounds, found %v", err)
+		}
+	}()
+	b.GetTile(-1, 0)
+}
@@ -0,0 +1,133 @@
+package lib
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// A Clock tells the time, and calls functions once some time has
+// passed. The game only ever waits on time through a clock, so tests
+// can swap the real one out for one they control.
+type Clock interface {
+	Now() time.Time
+	// Calls f in it's own goroutine once the duration has passed
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// A Timer waiting on a clock. Stop returns false if the timer already
+// went off, or was already stopped.
+type Timer interface {
+	Stop() bool
+}
+
+// The clock everything uses unless told otherwise, backed by the
+// time package
+var RealClock Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+// A clock that only moves when it's told to. Timers go off while
+// Advance is running, in the order they're due, and their functions
+// are called right there instead of in a goroutine. Once Advance
+// returns, everything that was due has happened.
+type FakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+	// Used to keep timers that are due at the same time in the order
+	// they were made
+	nextID int
+}
+
+func NewFakeClock(start time.Time) *FakeClock {
+	return &FakeClock{now: start}
+}
+
+func (clock *FakeClock) Now() time.Time {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	return clock.now
+}
+
+func (clock *FakeClock) AfterFunc(d time.Duration, f func()) Timer {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+
+	timer := &fakeTimer{clock: clock, when: clock.now.Add(d), f: f, id: clock.nextID}
+	clock.nextID++
+	clock.timers = append(clock.timers, timer)
+	return timer
+}
+
+// Moves the clock forward, setting off every timer that's due along
+// the way. Timers made by those functions go off too, if they're due
+// before the clock gets to where it's going.
+func (clock *FakeClock) Advance(d time.Duration) {
+	clock.mu.Lock()
+	end := clock.now.Add(d)
+
+	for {
+		sort.Slice(clock.timers, func(i, j int) bool {
+			a, b := clock.timers[i], clock.timers[j]
+			if a.when.Equal(b.when) {
+				return a.id < b.id
+			}
+			return a.when.Before(b.when)
+		})
+
+		if len(clock.timers) == 0 || clock.timers[0].when.After(end) {
+			break
+		}
+
+		timer := clock.timers[0]
+		clock.timers = clock.timers[1:]
+		clock.now = timer.when
+
+		// The function might make timers of it's own, so the clock
+		// can't be locked while it runs
+		clock.mu.Unlock()
+		timer.f()
+		clock.mu.Lock()
+	}
+
+	clock.now = end
+	clock.mu.Unlock()
+}
+
+// Returns the number of timers that haven't gone off or been stopped
+func (clock *FakeClock) Pending() int {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	return len(clock.timers)
+}
+
+type fakeTimer struct {
+	clock *FakeClock
+	when  time.Time
+	f     func()
+	id    int
+}
+
+func (timer *fakeTimer) Stop() bool {
+	clock := timer.clock
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+
+	for i, t := range clock.timers {
+		if t == timer {
+			clock.timers = append(clock.timers[:i], clock.timers[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
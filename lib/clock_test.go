@@ -0,0 +1,59 @@
+package lib
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFakeClockAdvance(t *testing.T) {
+	start := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+
+	var order []int
+	clock.AfterFunc(3*time.Second, func() { order = append(order, 3) })
+	clock.AfterFunc(time.Second, func() { order = append(order, 1) })
+	stopped := clock.AfterFunc(2*time.Second, func() { order = append(order, 2) })
+
+	if !stopped.Stop() {
+		t.Error("Stopping a pending timer returned false")
+	}
+
+	clock.Advance(time.Second)
+	if !reflect.DeepEqual(order, []int{1}) {
+		t.Errorf("Expected only the first timer to go off, found %v", order)
+	}
+
+	clock.Advance(5 * time.Second)
+	if !reflect.DeepEqual(order, []int{1, 3}) {
+		t.Errorf("Expected the timers to go off in order, found %v", order)
+	}
+
+	if now := clock.Now(); !now.Equal(start.Add(6 * time.Second)) {
+		t.Errorf("Clock is at %v, expected %v", now, start.Add(6*time.Second))
+	}
+
+	if stopped.Stop() {
+		t.Error("Stopping a stopped timer returned true")
+	}
+}
+
+func TestFakeClockChainedTimers(t *testing.T) {
+	clock := NewFakeClock(time.Time{})
+
+	// A timer that keeps setting itself up again, like a ticker
+	var times []time.Duration
+	var tick func()
+	tick = func() {
+		times = append(times, clock.Now().Sub(time.Time{}))
+		clock.AfterFunc(time.Second, tick)
+	}
+	clock.AfterFunc(time.Second, tick)
+
+	clock.Advance(3500 * time.Millisecond)
+
+	expected := []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}
+	if !reflect.DeepEqual(times, expected) {
+		t.Errorf("Expected the timer to go off at %v, found %v", expected, times)
+	}
+}
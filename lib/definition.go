@@ -0,0 +1,376 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"io"
+	"os"
+	"strings"
+)
+
+// A definition file describes a whole set of pieces, how they rotate
+// and kick, and the rules for where they come in and when the game is
+// over. It's written in JSON, like so:
+//
+//	{
+//	    "rules": {"centered": true, "lockOut": true},
+//	    "kicks": {"0>R": [[0, 0], [-1, 0]], "R>0": [[0, 0], [1, 0]]},
+//	    "pieces": [
+//	        {
+//	            "name": "P",
+//	            "color": "#ff69b4",
+//	            "states": [[".##", ".##", ".#."]],
+//	            "spawn": [0, 1]
+//	        }
+//	    ]
+//	}
+//
+// The rules are the fields of a Ruleset. Each piece has one, two or
+// four states, which are drawn top row first with a '#' for each tile.
+// A single state is pivoted to get the other three, two states take
+// turns, and four states are given in clockwise order, starting with
+// the one the piece spawns in. States are called 0, R, 2 and L in the
+// kick tables, just like SRS. A kick table maps a quarter turn, such
+// as "0>R" or "R>0", to the offsets to try in order, with y going up
+// the board. The rotation is only tried in place if [0, 0] is one of
+// them, so tables like SRS's list it first. The kicks at the top are
+// used by every piece that doesn't have a table of it's own, and a
+// rotation that isn't in either table is only tried in place. The
+// spawn offset moves the piece from where the rules would put it.
+type definitionFile struct {
+	Rules  Ruleset               `json:"rules"`
+	Kicks  map[string][][2]int   `json:"kicks"`
+	Pieces []definitionFilePiece `json:"pieces"`
+}
+
+type definitionFilePiece struct {
+	Name   string              `json:"name"`
+	Color  string              `json:"color"`
+	States [][]string          `json:"states"`
+	Spawn  [2]int              `json:"spawn"`
+	Kicks  map[string][][2]int `json:"kicks"`
+}
+
+// The pieces and rules loaded from a definition file. The pieces have
+// been registered, so they can be used like any other shape.
+type Definition struct {
+	Rules    Ruleset
+	Rotation RotationSystem
+	// Every piece in the file, in the order they were written
+	Shapes []Shape
+}
+
+// Returns the options that play a game with the definition's pieces
+// and rules. Each piece comes up once per bag.
+func (def *Definition) Options() []GameOption {
+	shapes := append([]Shape(nil), def.Shapes...)
+	return []GameOption{
+		WithRuleset(def.Rules),
+		WithRotationSystem(def.Rotation),
+		WithRandomizer(func(seed int64) Randomizer {
+			return NewBagRandomizerOf(seed, shapes...)
+		}),
+	}
+}
+
+// Loads a definition file from disk, see LoadDefinition
+func LoadDefinitionFile(path string) (*Definition, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	def, err := LoadDefinition(f)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+
+	return def, nil
+}
+
+// Reads a definition and registers it's pieces. Everything in it is
+// checked before anything is registered, so a bad definition doesn't
+// leave half of it's pieces behind. Like RegisterShape, it has to be
+// done before any games start.
+func LoadDefinition(r io.Reader) (*Definition, error) {
+	var file definitionFile
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("reading definition: %w", err)
+	}
+
+	if len(file.Pieces) == 0 {
+		return nil, fmt.Errorf("definition has no pieces")
+	}
+
+	defaultKicks, err := parseKicks(file.Kicks)
+	if err != nil {
+		return nil, err
+	}
+
+	type checkedPiece struct {
+		grid   TetGrid
+		masks  []*[]bool
+		color  color.RGBA
+		kicks  kickTable
+		offset Position
+	}
+
+	names := make(map[string]bool)
+	pieces := make([]checkedPiece, len(file.Pieces))
+	for i, fp := range file.Pieces {
+		name := fp.Name
+		if name == "" {
+			name = fmt.Sprintf("#%v", i+1)
+		} else if names[name] {
+			return nil, fmt.Errorf("piece %q is defined twice", name)
+		}
+		names[name] = true
+
+		piece := &pieces[i]
+		if piece.color, err = parseColor(fp.Color); err != nil {
+			return nil, fmt.Errorf("piece %v: %w", name, err)
+		}
+		if piece.grid, piece.masks, err = parseStates(fp.States); err != nil {
+			return nil, fmt.Errorf("piece %v: %w", name, err)
+		}
+
+		piece.kicks = defaultKicks
+		if fp.Kicks != nil {
+			if piece.kicks, err = parseKicks(fp.Kicks); err != nil {
+				return nil, fmt.Errorf("piece %v: %w", name, err)
+			}
+		}
+
+		piece.offset = Position{fp.Spawn[0], fp.Spawn[1]}
+	}
+
+	def := &Definition{Rules: file.Rules}
+	rotation := DefinedRotation{
+		masks: make(map[Shape][]*[]bool),
+		kicks: make(map[Shape]kickTable),
+	}
+	def.Rules.Offsets = make(map[Shape]Position)
+	for _, piece := range pieces {
+		s := RegisterShape(piece.grid, piece.color)
+		rotation.masks[s] = piece.masks
+		rotation.kicks[s] = piece.kicks
+		def.Rules.Offsets[s] = piece.offset
+		def.Shapes = append(def.Shapes, s)
+	}
+	def.Rotation = rotation
+
+	return def, nil
+}
+
+// Parses a color written like #rrggbb
+func parseColor(s string) (color.RGBA, error) {
+	c := color.RGBA{A: 255}
+	if len(s) != 7 || s[0] != '#' {
+		return c, fmt.Errorf("color %q should look like #rrggbb", s)
+	}
+
+	if _, err := fmt.Sscanf(s[1:], "%02x%02x%02x", &c.R, &c.G, &c.B); err != nil {
+		return c, fmt.Errorf("color %q should look like #rrggbb", s)
+	}
+
+	return c, nil
+}
+
+// Turns a piece's states into the grid it's registered with, and the
+// masks it goes through in order of left rotations
+func parseStates(states [][]string) (TetGrid, []*[]bool, error) {
+	grids := make([]TetGrid, len(states))
+	for i, rows := range states {
+		grid, err := parseMask(rows)
+		if err != nil {
+			return TetGrid{}, nil, fmt.Errorf("state %v: %w", i, err)
+		}
+
+		if i > 0 && grid.size != grids[0].size {
+			return TetGrid{}, nil, fmt.Errorf("state %v: expected %vx%v like the first state, found %vx%v",
+				i, grids[0].size, grids[0].size, grid.size, grid.size)
+		}
+		if i > 0 && tileCount(grid) != tileCount(grids[0]) {
+			return TetGrid{}, nil, fmt.Errorf("state %v: expected %v tiles like the first state, found %v",
+				i, tileCount(grids[0]), tileCount(grid))
+		}
+
+		grids[i] = grid
+	}
+
+	switch len(grids) {
+	case 1:
+		return grids[0], pivotRotations(grids[0]), nil
+	case 2:
+		return grids[0], fixedRotations(grids[0].grid, grids[1].grid, grids[0].grid, grids[1].grid), nil
+	case 4:
+		// Left rotations go through the clockwise states backwards
+		return grids[0], fixedRotations(grids[0].grid, grids[3].grid, grids[2].grid, grids[1].grid), nil
+	default:
+		return TetGrid{}, nil, fmt.Errorf("expected 1, 2 or 4 states, found %v", len(grids))
+	}
+}
+
+// Parses a mask drawn with a '#' for each tile and a '.' for each gap.
+// It has to be square, and every tile has to touch another one.
+func parseMask(rows []string) (TetGrid, error) {
+	size := len(rows)
+	if size == 0 {
+		return TetGrid{}, fmt.Errorf("mask is empty")
+	}
+
+	grid := TetGrid{grid: make([]bool, size*size), size: size}
+	for y, row := range rows {
+		if len(row) != size {
+			return TetGrid{}, fmt.Errorf("mask must be square, row %v is %v wide but there are %v rows", y, len(row), size)
+		}
+
+		for x, r := range row {
+			switch r {
+			case '#':
+				grid.grid[y*size+x] = true
+			case '.':
+			default:
+				return TetGrid{}, fmt.Errorf("row %v has %q in it, only '#' and '.' are allowed", y, r)
+			}
+		}
+	}
+
+	if tileCount(grid) == 0 {
+		return TetGrid{}, fmt.Errorf("mask has no tiles")
+	}
+	if !connected(grid) {
+		return TetGrid{}, fmt.Errorf("tiles must be connected:\n%v", strings.Join(rows, "\n"))
+	}
+
+	return grid, nil
+}
+
+func tileCount(grid TetGrid) int {
+	var count int
+	for _, filled := range grid.grid {
+		if filled {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Returns true if every tile can be reached from every other one by
+// stepping up, down, left or right
+func connected(grid TetGrid) bool {
+	seen := make([]bool, len(grid.grid))
+	var stack []int
+	for i, filled := range grid.grid {
+		if filled {
+			stack = append(stack, i)
+			seen[i] = true
+			break
+		}
+	}
+
+	reached := 0
+	for len(stack) > 0 {
+		i := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		reached++
+
+		x, y := i%grid.size, i/grid.size
+		for _, d := range [][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}} {
+			nx, ny := x+d[0], y+d[1]
+			if nx < 0 || nx >= grid.size || ny < 0 || ny >= grid.size {
+				continue
+			}
+
+			n := ny*grid.size + nx
+			if grid.grid[n] && !seen[n] {
+				seen[n] = true
+				stack = append(stack, n)
+			}
+		}
+	}
+
+	return reached == tileCount(grid)
+}
+
+// Kick offsets indexed by the rotation index being rotated from, and
+// then the one being rotated to
+type kickTable [4][4][]Position
+
+// The rotation index of each state. The index counts left rotations,
+// while the states go clockwise.
+var definitionStates = map[byte]int{'0': 0, 'R': 3, '2': 2, 'L': 1}
+
+func parseKicks(kicks map[string][][2]int) (kickTable, error) {
+	var table kickTable
+	for rotation, offsets := range kicks {
+		if len(rotation) != 3 || rotation[1] != '>' {
+			return table, fmt.Errorf("kick %q should look like 0>R, using the states 0, R, 2 and L", rotation)
+		}
+
+		from, okFrom := definitionStates[rotation[0]]
+		to, okTo := definitionStates[rotation[2]]
+		if !okFrom || !okTo {
+			return table, fmt.Errorf("kick %q should look like 0>R, using the states 0, R, 2 and L", rotation)
+		}
+		// Pieces only ever turn a quarter at a time, so nothing else
+		// would ever be used
+		if turn := (from - to + 4) % 4; turn != 1 && turn != 3 {
+			return table, fmt.Errorf("kick %q isn't a turn to the left or right", rotation)
+		}
+
+		for _, offset := range offsets {
+			table[from][to] = append(table[from][to], Position{offset[0], offset[1]})
+		}
+	}
+
+	return table, nil
+}
+
+// The rotation system for pieces loaded from a definition file. Each
+// rotation tries the offsets in the piece's kick table in order, and
+// is only tried in place if the table has nothing for it. Any other
+// shape rotates like it does under the classic rotation system, but
+// without being pushed back onto the board.
+type DefinedRotation struct {
+	masks map[Shape][]*[]bool
+	kicks map[Shape]kickTable
+}
+
+func (rs DefinedRotation) Spawn(tet *Tetromino) {
+	if masks, ok := rs.masks[tet.shape]; ok {
+		tet.setMasks(masks, 0)
+		return
+	}
+
+	tet.setMasks(rotations[tet.shape], 0)
+}
+
+func (rs DefinedRotation) Rotate(tet ActiveTetromino, isLeft bool, board *Board) (ActiveTetromino, bool) {
+	rotationFunc, rotationInverse := rotationFuncs(tet.Tetromino, isLeft)
+
+	from := tet.rotationIdx
+	rotationFunc()
+	kicks := rs.kicks[tet.shape][from][tet.rotationIdx]
+	if len(kicks) == 0 {
+		kicks = []Position{{0, 0}}
+	}
+
+	for _, kick := range kicks {
+		projectedTet := tet
+		projectedTet.x += kick.x
+		projectedTet.y += kick.y
+
+		if projectedTet.fits(board) {
+			return projectedTet, true
+		}
+	}
+
+	rotationInverse()
+	return tet, false
+}
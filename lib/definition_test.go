@@ -0,0 +1,183 @@
+package lib
+
+import (
+	"image/color"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testDefinition = `{
+	"rules": {"centered": true, "lockOut": true},
+	"kicks": {"0>R": [[0, 0], [-1, 0]]},
+	"pieces": [
+		{
+			"name": "P",
+			"color": "#ff69b4",
+			"states": [[".##", ".##", ".#."]],
+			"spawn": [1, 0]
+		},
+		{
+			"name": "V",
+			"color": "#102030",
+			"states": [
+				["#..", "#..", "###"],
+				["###", "#..", "#.."],
+				["###", "..#", "..#"],
+				["..#", "..#", "###"]
+			],
+			"kicks": {"0>L": [[0, 0], [0, 1]]}
+		}
+	]
+}`
+
+func TestLoadDefinition(t *testing.T) {
+	keepShapes(t)
+
+	def, err := LoadDefinition(strings.NewReader(testDefinition))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(def.Shapes) != 2 {
+		t.Fatalf("Expected two pieces, found %v", def.Shapes)
+	}
+	p, v := def.Shapes[0], def.Shapes[1]
+
+	if c, _ := ShapeColor(v); c != (color.RGBA{0x10, 0x20, 0x30, 255}) {
+		t.Errorf("Expected the color to be read, found %v", c)
+	}
+	if !def.Rules.Centered || !def.Rules.LockOut {
+		t.Errorf("Expected the rules to be read, found %+v", def.Rules)
+	}
+
+	// The spawn offset moves the piece over from the middle
+	board := &Board{}
+	tet := NewTet(p)
+	def.Rotation.Spawn(tet)
+	centered := def.Rules
+	centered.Offsets = nil
+	if found, expected := def.Rules.SpawnPosition(tet, board), centered.SpawnPosition(tet, board); found.x != expected.x+1 {
+		t.Errorf("Expected the piece to spawn at x=%v, found %v", expected.x+1, found.x)
+	}
+
+	// Turning clockwise goes through the states in the order they were
+	// written
+	tet = NewTet(v)
+	def.Rotation.Spawn(tet)
+	at := ActiveTetromino{tet, Position{3, 10}}
+	at, ok := def.Rotation.Rotate(at, false, board)
+	if !ok || !reflect.DeepEqual(tet.GetMask(), []bool{
+		true, true, true,
+		true, false, false,
+		true, false, false,
+	}) {
+		t.Errorf("Expected the second state after turning right, found %v", tet.GetMask())
+	}
+
+	// Back in the first state, block the spot so turning left needs
+	// the piece's own kick
+	at, _ = def.Rotation.Rotate(at, true, board)
+	board.SetTile(C1, 5, 8)
+	if at, ok = def.Rotation.Rotate(at, true, board); !ok || at.y != 11 {
+		t.Errorf("Expected the piece to kick up, found it at y=%v", at.y)
+	}
+
+	// A rotation with kicks is only tried in place if they say so. P's
+	// first kick is in place, so it turns right where it is
+	tet = NewTet(p)
+	def.Rotation.Spawn(tet)
+	if at, ok = def.Rotation.Rotate(ActiveTetromino{tet, Position{3, 10}}, false, &Board{}); !ok || at.x != 3 {
+		t.Errorf("Expected the piece to turn in place, found it at x=%v", at.x)
+	}
+
+	def, err = LoadDefinition(strings.NewReader(`{
+		"kicks": {"0>R": [[-1, 0]]},
+		"pieces": [{"name": "Q", "color": "#ffffff", "states": [[".##", ".##", ".#."]]}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tet = NewTet(def.Shapes[0])
+	def.Rotation.Spawn(tet)
+	if at, ok = def.Rotation.Rotate(ActiveTetromino{tet, Position{3, 10}}, false, &Board{}); !ok || at.x != 2 {
+		t.Errorf("Expected the piece to kick over even with room to turn, found it at x=%v", at.x)
+	}
+}
+
+func TestDefinitionPlay(t *testing.T) {
+	keepShapes(t)
+
+	def, err := LoadDefinition(strings.NewReader(testDefinition))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := rand.New(rand.NewSource(1))
+
+	game := NewGame(0, 1, def.Options()...)
+	seen := make(map[Shape]bool)
+	for i := 0; !game.IsGameover() && i < 10000; i++ {
+		seen[game.controller.tet.shape] = true
+		game.Tick(Movement(r.Intn(int(MOVE_FORCE_DOWN))))
+		game.Tick(MOVE_FORCE_DOWN)
+	}
+
+	if !game.IsGameover() {
+		t.Error("Expected the game to end")
+	}
+	if len(seen) != 2 || !seen[def.Shapes[0]] || !seen[def.Shapes[1]] {
+		t.Errorf("Expected only the defined pieces to come up, found %v", seen)
+	}
+}
+
+func TestLoadDefinitionErrors(t *testing.T) {
+	piece := func(fields string) string {
+		return `{"pieces": [{"name": "X", "color": "#ffffff", ` + fields + `}]}`
+	}
+
+	tests := []struct {
+		name       string
+		definition string
+		// Part of the error message that points to the problem
+		expected string
+	}{
+		{"not json", `{`, "reading definition"},
+		{"unknown field", `{"peices": []}`, "peices"},
+		{"no pieces", `{"pieces": []}`, "no pieces"},
+		{"not square", piece(`"states": [["##", "##", ".."]]`), "square"},
+		{"empty", piece(`"states": [[]]`), "empty"},
+		{"no tiles", piece(`"states": [["..", ".."]]`), "no tiles"},
+		{"bad tile", piece(`"states": [["#x", "##"]]`), "'x'"},
+		{"disconnected", piece(`"states": [["#.", ".#"]]`), "connected"},
+		{"three states", piece(`"states": [["#"], ["#"], ["#"]]`), "1, 2 or 4 states"},
+		{"sizes differ", piece(`"states": [["#"], ["##", ".."]]`), "1x1"},
+		{"tiles differ", piece(`"states": [["##", ".."], ["#.", ".."]]`), "2 tiles"},
+		{"bad color", `{"pieces": [{"color": "pink", "states": [["#"]]}]}`, "#rrggbb"},
+		{"bad kick", piece(`"states": [["#"]], "kicks": {"0>X": []}`), "0>R"},
+		{"half turn", piece(`"states": [["#"]], "kicks": {"0>2": [[0, 0]]}`), "left or right"},
+		{"no turn", `{"kicks": {"R>R": [[0, 0]]}, "pieces": [{"color": "#ffffff", "states": [["#"]]}]}`, "left or right"},
+		{"twice", `{"pieces": [
+			{"name": "X", "color": "#ffffff", "states": [["#"]]},
+			{"name": "X", "color": "#ffffff", "states": [["#"]]}
+		]}`, "twice"},
+	}
+
+	keepShapes(t)
+	for _, test := range tests {
+		registered := len(Shapes())
+
+		_, err := LoadDefinition(strings.NewReader(test.definition))
+		if err == nil {
+			t.Errorf("%v: expected an error", test.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("%v: expected the error to mention %q, found %q", test.name, test.expected, err)
+		}
+
+		if len(Shapes()) != registered {
+			t.Errorf("%v: pieces were registered from a bad definition", test.name)
+		}
+	}
+}
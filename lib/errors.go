@@ -0,0 +1,24 @@
+package lib
+
+import (
+	"errors"
+)
+
+// Errors returned by the Try variants of functions that otherwise
+// panic on bad input. They're wrapped with the details of what was
+// wrong, so check for them with errors.Is.
+var (
+	// Coordinates that aren't on the board
+	ErrOutOfBounds = errors.New("out of bounds")
+	// A shape that isn't one of the tetrominos or a registered shape,
+	// or a grid that a shape can't be made from
+	ErrInvalidShape = errors.New("invalid shape")
+	// A tile color that isn't EMPTY, one of the tetrominos' colors or
+	// a registered shape's color
+	ErrInvalidTile = errors.New("invalid tile")
+	// A Movement or Direction that doesn't exist
+	ErrInvalidMove = errors.New("invalid move")
+	// A saved game that can't be loaded, because it's from another
+	// version or doesn't make sense
+	ErrInvalidSave = errors.New("invalid save")
+)
@@ -1,6 +1,8 @@
 package lib
 
 import (
+	"context"
+	"fmt"
 	"time"
 )
 
@@ -23,9 +25,11 @@ const (
 	RIGHT
 )
 
+// Where tetrominos come in under the classic rules
 const STARTING_X = 4
 const STARTING_Y = 21
 
+// The first row above the ones the player can see
 const GAMEOVER_LINE = 20
 
 func NewActiveTet(t *Tetromino) ActiveTetromino {
@@ -38,7 +42,19 @@ func NewActiveTet(t *Tetromino) ActiveTetromino {
 	}
 }
 
+// Returns the tetromino moved a tile in the given direction. Panics if
+// the direction doesn't exist, see TryMove.
 func (tet ActiveTetromino) Move(dir Direction) ActiveTetromino {
+	moved, err := tet.TryMove(dir)
+	if err != nil {
+		panic(err)
+	}
+
+	return moved
+}
+
+// Like Move, but returns ErrInvalidMove instead of panicking
+func (tet ActiveTetromino) TryMove(dir Direction) (ActiveTetromino, error) {
 	switch dir {
 	case UP:
 		tet.y += 1
@@ -49,14 +65,14 @@ func (tet ActiveTetromino) Move(dir Direction) ActiveTetromino {
 	case RIGHT:
 		tet.x += 1
 	default:
-		panic("Illegal direction passed")
+		return tet, fmt.Errorf("%w: direction %v", ErrInvalidMove, dir)
 	}
 
-	return tet
+	return tet, nil
 }
 
-func (tet ActiveTetromino) GetPos() (int, int) {
-	return tet.x, tet.y
+func (p Position) GetPos() (int, int) {
+	return p.x, p.y
 }
 
 // Returns a list of positions for use in a board, where the tiles appear
@@ -82,32 +98,18 @@ func (tet ActiveTetromino) ListPositions() []Position {
 
 // Returns true if the tetromino can be moved in the given direction
 // without intersecting any tiles in the board, and within the
-// boundaries of the board
+// boundaries of the board. Directions that don't exist can't be moved
+// in.
 func (tet ActiveTetromino) CanMove(dir Direction, board *Board) bool {
-	currentPos := tet.ListPositions()
-	projectedPos := tet.Move(dir).ListPositions()
-
-	// Test whether a position is in a position that the tetromino is
-	// currently in. It's okay to occupy a future space that' we're
-	// already inside of
-	isInCurrentPos := func(p Position) bool {
-		for _, c := range currentPos {
-			if c == p {
-				return true
-			}
-		}
-
-		return false
-	}
+	moved, err := tet.TryMove(dir)
+	return err == nil && moved.fits(board)
+}
 
-	for _, p := range projectedPos {
-		if isInCurrentPos(p) {
-			continue
-		} else if p.x < 0 ||
-			p.x >= BOARD_WIDTH ||
-			p.y < 0 ||
-			p.y >= BOARD_HEIGHT ||
-			!board.IsEmpty(p.x, p.y) {
+// Returns true if every tile of the tetromino is within the
+// boundaries of the board, and doesn't overlap any other tiles
+func (tet ActiveTetromino) fits(board *Board) bool {
+	for _, p := range tet.ListPositions() {
+		if board.Blocked(p.x, p.y) {
 			return false
 		}
 	}
@@ -115,79 +117,146 @@ func (tet ActiveTetromino) CanMove(dir Direction, board *Board) bool {
 	return true
 }
 
+// Draws the tetromino's tiles onto the board in it's color
+func (tet ActiveTetromino) stamp(board *Board) {
+	for _, p := range tet.ListPositions() {
+		board.SetTile(ShapeToTC(tet.shape), p.x, p.y)
+	}
+}
+
 const DEFAULT_DURATION = time.Second
 
 // A BoardController is an entity that manages the state of a board
 // and an active tetromino. It moves the tetromino around with respect
 // to the board, and can glue the tetromino to the board as one would
-// expect with tetris.
+// expect with tetris. The board only ever holds tiles that have been
+// locked in place, the active tetromino is kept apart from it until
+// then.
 type BoardController struct {
 	board      *Board
 	tet        ActiveTetromino
+	rotation   RotationSystem
+	rules      Ruleset
 	isGameover bool
+	// Whether the last thing to happen to the active tetromino was a
+	// rotation, and how far that rotation kicked it. Needed to spot
+	// T-spins when it locks
+	lastRotated bool
+	lastKick    Position
+	// How many tetrominos have come into play
+	spawned int
 }
 
-func NewBoardController(board *Board, tet *Tetromino) *BoardController {
-	ctl := &BoardController{board: board}
+// Creates a board controller that plays by the classic rules
+func NewBoardController(board *Board, tet *Tetromino, rotation RotationSystem) *BoardController {
+	return NewBoardControllerWithRules(board, tet, rotation, ClassicRuleset())
+}
+
+// Creates a board controller that spawns tetrominos and tops out by
+// the given rules
+func NewBoardControllerWithRules(board *Board, tet *Tetromino, rotation RotationSystem, rules Ruleset) *BoardController {
+	ctl := &BoardController{board: board, rotation: rotation, rules: rules}
 	ctl.NextTet(tet)
 
 	return ctl
 }
 
-// Returns the number of lines cleared, if any and sets the next
-// tetromino as the one passed.
+// Locks the active tetromino in place, and sets the next tetromino as
+// the one passed. Returns the number of lines cleared, if any.
 func (ctl *BoardController) NextTet(next *Tetromino) int {
 	// This is the value of ctl.tet before it's been set. Need to do a
 	// comparison with this so don't compare against a nil value when
 	// checking the gameover line
 	initTet := ActiveTetromino{}
-	lines := ctl.board.Tetris()
-
-	if lines == 0 && ctl.tet != initTet {
-		// Check for whether they are on the gameover line
-		for _, p := range ctl.tet.ListPositions() {
-			if p.y == GAMEOVER_LINE {
-				ctl.isGameover = true
-				return 0
-			}
-		}
-	}
-
-	// NewActiveTet will handle setting the default position
-	ctl.tet = NewActiveTet(next)
-
-	// Do a quick gameover check
-	for _, p := range ctl.tet.ListPositions() {
-		if !ctl.board.IsEmpty(p.x, p.y) {
-			ctl.isGameover = true
+	if ctl.tet != initTet {
+		if ctl.settle(); ctl.isGameover {
 			return 0
 		}
 	}
 
-	// Set new tiles
-	for _, p := range ctl.tet.ListPositions() {
-		ctl.board.SetTile(ShapeToTC(ctl.tet.shape), p.x, p.y)
+	lines := ctl.board.Tetris()
+
+	if !ctl.spawn(next) {
+		return 0
 	}
 
 	return lines
 }
 
-// This is a helper function that let's us pass a function, and
-// inbetween, we'll unset and then set the tiles. This ensures we
-// don't leave any copies around. If no state changes, the tiles
-// should be put where they were before
-func (ctl *BoardController) updateTiles(callback func() ActiveTetromino) {
-	// Unset the tiles
-	for _, p := range ctl.tet.ListPositions() {
-		ctl.board.SetTile(EMPTY, p.x, p.y)
+// Stamps the active tetromino onto the board, and returns the rows it
+// filled up, counting from the bottom. The rows are left where they
+// are, and nothing replaces the tetromino, that's up to the caller.
+// Ends the game if it locked out without filling any rows.
+func (ctl *BoardController) settle() []int {
+	ctl.tet.stamp(ctl.board)
+	rows := ctl.board.FullLines()
+
+	if len(rows) == 0 && ctl.rules.lockedOut(ctl.tet.ListPositions(), ctl.board) {
+		ctl.isGameover = true
 	}
 
-	ctl.tet = callback()
+	return rows
+}
 
-	// Set the new tiles
-	for _, p := range ctl.tet.ListPositions() {
-		ctl.board.SetTile(ShapeToTC(ctl.tet.shape), p.x, p.y)
+// Places a new active tetromino at the top of the board. Returns
+// false, and ends the game, if there isn't any room for it.
+func (ctl *BoardController) spawn(next *Tetromino) bool {
+	// The rotation system decides which way the tetromino faces, and
+	// the rules decide where it goes from there
+	ctl.rotation.Spawn(next)
+	ctl.tet = ActiveTetromino{next, ctl.rules.SpawnPosition(next, ctl.board)}
+	ctl.lastRotated = false
+	ctl.spawned++
+
+	return ctl.checkSpawn()
+}
+
+// Places a new active tetromino at the top of the board like spawn,
+// but turned left or right first if it can be. This is the initial
+// rotation system, the tetromino comes in already facing the way the
+// player wants. Only where it ends up has to be free, so turning it
+// can save the game if it doesn't fit the way it spawns.
+func (ctl *BoardController) spawnRotated(next *Tetromino, isLeft bool) bool {
+	ctl.rotation.Spawn(next)
+	tet := ActiveTetromino{next, ctl.rules.SpawnPosition(next, ctl.board)}
+	ctl.tet, _ = ctl.rotation.Rotate(tet, isLeft, ctl.board)
+	ctl.lastRotated = false
+	ctl.spawned++
+
+	return ctl.checkSpawn()
+}
+
+// Ends the game if the active tetromino doesn't fit on the board,
+// which means there wasn't any room for it to come in. Returns false
+// if it did.
+func (ctl *BoardController) checkSpawn() bool {
+	if !ctl.tet.fits(ctl.board) {
+		ctl.isGameover = true
+		return false
 	}
+
+	return true
+}
+
+// Swaps the active tetromino for another one, without locking it to
+// the board. The replacement starts again from the top, and the
+// tetromino that was swapped out is returned in it's spawn
+// orientation so it can be held onto.
+func (ctl *BoardController) Swap(next *Tetromino) *Tetromino {
+	prev := ctl.tet.Tetromino
+
+	ctl.rotation.Spawn(prev)
+	ctl.spawn(next)
+
+	return prev
+}
+
+// Returns a copy of the board with the active tetromino drawn on top
+// of the locked tiles, which is what the player actually sees.
+func (ctl *BoardController) View() Board {
+	view := ctl.board.Clone()
+	ctl.tet.stamp(&view)
+	return view
 }
 
 // Helper method that conveniently checks whether a tile can move
@@ -198,126 +267,110 @@ func (ctl *BoardController) CanMoveDown() bool {
 }
 
 // Move will idempotently move the active tetris piece. If it can't be
-// moved, then it won't be moved.
-func (ctl *BoardController) Move(dir Direction) {
-	if ctl.tet.CanMove(dir, ctl.board) {
-		ctl.updateTiles(func() ActiveTetromino {
-			return ctl.tet.Move(dir)
-		})
+// moved, then it won't be moved. Returns whether it moved.
+func (ctl *BoardController) Move(dir Direction) bool {
+	if !ctl.tet.CanMove(dir, ctl.board) {
+		return false
 	}
-}
 
-// Core rotation
-func (ctl *BoardController) rotate(isLeft bool) {
-	var rotationFunc, rotationInverse func()
-
-	if isLeft {
-		rotationFunc = ctl.tet.RotLeft
-		rotationInverse = ctl.tet.RotRight
-	} else {
-		rotationFunc = ctl.tet.RotRight
-		rotationInverse = ctl.tet.RotLeft
-	}
-
-	ctl.updateTiles(func() ActiveTetromino {
-		// Apply the rotation
-		rotationFunc()
-
-		// Consider all edge cases
-		// 1. Pushing to the left on the X-Axis
-		// 2. Pushing up on the Y-Axis
-		// 3. Pushing to the right on the X-Axis
-		// 4. Pushing down on th Y- axis
-		minX := 0
-		minY := 0
-		maxX := BOARD_WIDTH - 1
-		maxY := BOARD_HEIGHT - 1
-		for _, p := range ctl.tet.ListPositions() {
-			// Find minimum and maximum x and y values
-			if p.x > maxX {
-				maxX = p.x
-			} else if p.x < minX {
-				minX = p.x
-			}
-
-			if p.y < minY {
-				minY = p.y
-			} else if p.y > maxY {
-				maxY = p.y
-			}
-		}
-
-		// Shift in needed directions so it's in bounds, then check for
-		// any collisions
-		var deltaX, deltaY int
-		var yDir, xDir Direction
+	ctl.tet = ctl.tet.Move(dir)
+	ctl.lastRotated = false
+	return true
+}
 
-		// The direction and delta we apply depends on which threshold
-		// was crossed.
-		if minX < 0 {
-			deltaX = 0 - minX
-			xDir = RIGHT
-		} else {
-			deltaX = maxX - (BOARD_WIDTH - 1)
-			xDir = LEFT
-		}
+// Moves the active tetris piece as far as it can go in a direction.
+// Returns whether it moved at all.
+func (ctl *BoardController) Shift(dir Direction) bool {
+	var moved bool
+	for ctl.Move(dir) {
+		moved = true
+	}
 
-		if minY < 0 {
-			deltaY = 0 - minY
-			yDir = UP
-		} else {
-			deltaY = maxY - (BOARD_HEIGHT - 1)
-			yDir = DOWN
-		}
+	return moved
+}
 
-		projectedTet := ctl.tet
-		for i := 0; i < deltaX; i++ {
-			projectedTet = projectedTet.Move(xDir)
-		}
-		for i := 0; i < deltaY; i++ {
-			projectedTet = projectedTet.Move(yDir)
-		}
+// Core rotation. How the tetromino rotates, and where it's allowed to
+// end up, is entirely up to the rotation system
+func (ctl *BoardController) rotate(isLeft bool) bool {
+	prev := ctl.tet.Position
 
-		// Check for internal collisions
-		var colliding bool
-		for _, p := range projectedTet.ListPositions() {
-			if !ctl.board.IsEmpty(p.x, p.y) {
-				colliding = true
-				break
-			}
-		}
+	var rotated bool
+	ctl.tet, rotated = ctl.rotation.Rotate(ctl.tet, isLeft, ctl.board)
+	if rotated {
+		ctl.lastRotated = true
+		ctl.lastKick = Position{ctl.tet.x - prev.x, ctl.tet.y - prev.y}
+	}
 
-		if colliding {
-			// Undo the rotation, the operation is idempotent
-			rotationInverse()
-		}
-		return projectedTet
-	})
+	return rotated
 }
 
 // Attempting to rotate left or right will rotate in place if
-// possible, and possibly shift the tetromino along the x-axis to make
-// it fit. So it will rotate AND possibly move the tetromino
-func (ctl *BoardController) RotLeft() {
-	ctl.rotate(true)
+// possible, and possibly kick the tetromino to a nearby position to
+// make it fit. So it will rotate AND possibly move the tetromino.
+// Returns whether the rotation happened.
+func (ctl *BoardController) RotLeft() bool {
+	return ctl.rotate(true)
 }
 
-func (ctl *BoardController) RotRight() {
-	ctl.rotate(false)
+func (ctl *BoardController) RotRight() bool {
+	return ctl.rotate(false)
+}
+
+// Returns the active tetromino as it would be after falling as far
+// as it can. Nothing is changed, this is only a projection.
+func (ctl *BoardController) dropped() ActiveTetromino {
+	// Move as far down as possible
+	projectedTet := ctl.tet
+	for projectedTet.CanMove(DOWN, ctl.board) {
+		projectedTet = projectedTet.Move(DOWN)
+	}
+
+	return projectedTet
 }
 
 // Slam will have a tetromino fall all the way to the bottom of the
 // board, or until it reaches something along it's path to the bottom.
-func (ctl *BoardController) Slam() {
-	ctl.updateTiles(func() ActiveTetromino {
-		// Move as far down as possible
-		projectedTet := ctl.tet
-		for projectedTet.CanMove(DOWN, ctl.board) {
-			projectedTet = projectedTet.Move(DOWN)
-		}
+// Returns how many rows it fell.
+func (ctl *BoardController) Slam() int {
+	dropped := ctl.dropped()
+	rows := ctl.tet.y - dropped.y
+
+	ctl.tet = dropped
+	if rows > 0 {
+		ctl.lastRotated = false
+	}
 
-		return projectedTet
-	})
+	return rows
+}
+
+// Locks the active tetromino in place and brings in the next one,
+// recording everything about the lock that matters for scoring. If
+// next is nil, the full rows are left on the board and nothing is
+// brought in, so the game can wait before doing either.
+func (ctl *BoardController) lock(next *Tetromino, result *TickResult) {
+	result.TSpin = ctl.TSpin()
+	result.Consumed = true
+
+	if next != nil {
+		result.Lines = ctl.NextTet(next)
+		result.PerfectClear = result.Lines > 0 && ctl.board.IsClear()
+		return
+	}
+
+	result.Rows = ctl.settle()
+	result.Lines = len(result.Rows)
+
+	// See what the board will look like once they're gone
+	cleared := ctl.board.Clone()
+	cleared.Tetris()
+	result.PerfectClear = result.Lines > 0 && cleared.IsClear()
+}
+
+// Ghost returns the positions the active tetromino would land in if it
+// were slammed, without changing the board. Useful for showing the
+// player where a piece is headed.
+func (ctl *BoardController) Ghost() []Position {
+	return ctl.dropped().ListPositions()
 }
 
 type Movement int
@@ -331,49 +384,77 @@ const (
 	MOVE_ROTATE_LEFT
 	MOVE_ROTATE_RIGHT
 	MOVE_FORCE_DOWN
+	MOVE_HOLD
+	// Moves as far as possible in a direction, all in one go
+	MOVE_SHIFT_LEFT
+	MOVE_SHIFT_RIGHT
+	// Keep this last, it marks the end of the moves
+	maxMovement = MOVE_SHIFT_RIGHT
 )
 
+// Returns true if the move is one of the moves above
+func (move Movement) Valid() bool {
+	return move >= MOVE_UP && move <= maxMovement
+}
+
+// Describes what happened to the board during a tick
+type TickResult struct {
+	// Number of lines cleared, if the tetromino was locked
+	Lines int
+	// Whether the tetromino was locked, consuming the next one
+	Consumed bool
+	// Whether the tetromino moved or rotated
+	Moved bool
+	// Number of rows the tetromino fell when it was slammed
+	Dropped int
+	// The kind of T-spin the tetromino was locked with, if any
+	TSpin TSpin
+	// Whether the lines cleared left the board completely empty
+	PerfectClear bool
+	// The full rows, if they were left on the board to be cleared later
+	Rows []int
+}
+
 // Tick will apply some sort of move and atomically update the board
 // with that given move. The board before and after tick will always
-// be in a consistent sensible state.
-//
-// Returns number of lines cleared between any movement, and whether
-// the tetromino was consumed
-func (ctl *BoardController) Tick(move Movement, next *Tetromino) (int, bool) {
-	var lines int
-	var consumed bool
-
-	if move <= MOVE_RIGHT {
+// be in a consistent sensible state. If the tetromino locks, next is
+// brought in to replace it, see lock for when it's nil.
+// Moves that aren't valid do nothing at all.
+func (ctl *BoardController) Tick(move Movement, next *Tetromino) TickResult {
+	var result TickResult
+
+	if !move.Valid() {
+		return result
+	} else if move <= MOVE_RIGHT {
 		// Movement must be a direction
-		ctl.Move(Direction(move))
+		result.Moved = ctl.Move(Direction(move))
 	} else {
 		switch move {
+		case MOVE_SHIFT_LEFT:
+			result.Moved = ctl.Shift(LEFT)
+		case MOVE_SHIFT_RIGHT:
+			result.Moved = ctl.Shift(RIGHT)
 		case MOVE_ROTATE_LEFT:
-			ctl.RotLeft()
+			result.Moved = ctl.RotLeft()
 		case MOVE_ROTATE_RIGHT:
-			ctl.RotRight()
+			result.Moved = ctl.RotRight()
 		case MOVE_SLAM:
-			if ctl.tet.CanMove(DOWN, ctl.board) {
-				ctl.Slam()
-			} else {
-				// If slam is double tapped, treat it as the user
-				// locking the tile in place, send the next tet over
-				lines = ctl.NextTet(next)
-				consumed = true
-			}
+			// A hard drop locks the tetromino in place right away,
+			// there's no lock delay
+			result.Dropped = ctl.Slam()
+			ctl.lock(next, &result)
 		case MOVE_FORCE_DOWN:
 			// This doesn't come from user input, but from a timer. It
 			// can potentially trigger next tet if it's at the bottom
 			if ctl.tet.CanMove(DOWN, ctl.board) {
-				ctl.Move(DOWN)
+				result.Moved = ctl.Move(DOWN)
 			} else {
-				lines = ctl.NextTet(next)
-				consumed = true
+				ctl.lock(next, &result)
 			}
 		}
 	}
 
-	return lines, consumed
+	return result
 }
 
 type Game struct {
@@ -383,51 +464,217 @@ type Game struct {
 	ticks         int
 	startingLevel int
 	controller    *BoardController
-	nextTet       *Tetromino
-	tetSource     chan *Tetromino
+	rotation      RotationSystem
+	scorer        Scorer
+	// How fast tetrominos fall at a given level, and what level the
+	// game is on after clearing some number of lines
+	gravity  func(level int) time.Duration
+	leveling func(startingLevel, lines int) int
+	// Where the shapes of upcoming tetrominos come from, the seed it
+	// was made with and how many shapes it's dealt so far
+	newRandomizer func(seed int64) Randomizer
+	randomizer    Randomizer
+	seed          int64
+	dealt         int
+	// The upcoming tetrominos, in the order they'll be played. It's
+	// always kept full, so the first one is the next tetromino.
+	preview []*Tetromino
+	// The tetromino put aside with a hold, if any. A hold can only be
+	// used once per tetromino, until it's locked in place.
+	heldTet  *Tetromino
+	holdUsed bool
+	// How long a tetromino can sit on the ground before it locks, and
+	// how many times moving it can restart that delay. The timer only
+	// exists while the game is being played.
+	lockDelay  time.Duration
+	maxResets  int
+	lockTimer  delayTimer
+	lockArmed  bool
+	lockResets int
+	// How long full rows stay on the board before they're cleared, and
+	// how long it takes the next tetromino to come in after a lock.
+	// Like the lock delay, these only happen while the game is being
+	// played or stepped, otherwise there are no timers.
+	lineClearDelay time.Duration
+	entryDelay     time.Duration
+	clearTimer     delayTimer
+	entryTimer     delayTimer
+	// What the game is doing, the rows waiting to be cleared, and the
+	// moves made while waiting, which are made once the next tetromino
+	// comes in
+	phase    Phase
+	clearing []int
+	buffered []Movement
+	// The size of the board, in columns and visible rows
+	width   int
+	visible int
+	// Where tetrominos come in, and when the game is over
+	rules Ruleset
+	// Reports whether a button is held down, for rotating and holding
+	// tetrominos as they come in. Nil if the game can't see buttons
+	held func(button Movement) bool
+	// Every timer Play uses waits on this clock
+	clock Clock
+	// How long a frame lasts when the game is moved forward with Step,
+	// and what's been counted so far
+	frame time.Duration
+	steps stepState
 }
 
-func TetFactory(seed int64) chan *Tetromino {
-	tets := make(chan *Tetromino)
+// Counts down one of the game's delays. Play uses a ResetTimer, and
+// Step counts frames instead
+type delayTimer interface {
+	Reset()
+	Stop()
+}
 
-	go func() {
-		for shape := range ShapeGenerator(seed) {
-			tets <- NewTet(shape)
-		}
-	}()
+// A GameOption changes some part of how a game is set up. They're
+// applied in order by NewGame, before the first tetromino is placed
+type GameOption func(*Game)
+
+// Sets the rotation system the game uses. Games use the classic
+// rotation system by default
+func WithRotationSystem(rotation RotationSystem) GameOption {
+	return func(game *Game) {
+		game.rotation = rotation
+	}
+}
+
+// Sets how the game is scored. Games use classic scoring by default
+func WithScorer(scorer Scorer) GameOption {
+	return func(game *Game) {
+		game.scorer = scorer
+	}
+}
+
+// Sets the clock that Play waits on. Games use the real clock by
+// default, tests can pass in a FakeClock to control time themselves
+func WithClock(clock Clock) GameOption {
+	return func(game *Game) {
+		game.clock = clock
+	}
+}
+
+const DEFAULT_LOCK_DELAY = 500 * time.Millisecond
+const DEFAULT_LOCK_RESETS = 15
+
+// Sets how long a tetromino can rest on the ground before it locks,
+// and how many successful moves or rotations can restart the delay
+// before it locks regardless. A delay of zero turns it off, so
+// tetrominos lock as soon as gravity can't move them any further.
+func WithLockDelay(delay time.Duration, maxResets int) GameOption {
+	return func(game *Game) {
+		game.lockDelay = delay
+		game.maxResets = maxResets
+	}
+}
+
+const DEFAULT_PREVIEW = 1
+const MIN_PREVIEW = 1
+const MAX_PREVIEW = 7
+
+// Sets how many upcoming tetrominos the player can see. Values
+// outside of MIN_PREVIEW and MAX_PREVIEW are clamped to that range.
+func WithPreview(depth int) GameOption {
+	if depth < MIN_PREVIEW {
+		depth = MIN_PREVIEW
+	} else if depth > MAX_PREVIEW {
+		depth = MAX_PREVIEW
+	}
+
+	return func(game *Game) {
+		game.preview = make([]*Tetromino, depth)
+	}
+}
+
+// Sets the size of the board, in columns and the rows the player can
+// see. Games are played on a 10 by 20 board by default. Sizes under
+// MIN_BOARD_WIDTH and MIN_BOARD_VISIBLE are raised to them, just like
+// NewBoard does.
+func WithBoardSize(width, visible int) GameOption {
+	if width < MIN_BOARD_WIDTH {
+		width = MIN_BOARD_WIDTH
+	}
+	if visible < MIN_BOARD_VISIBLE {
+		visible = MIN_BOARD_VISIBLE
+	}
 
-	return tets
+	return func(game *Game) {
+		game.width = width
+		game.visible = visible
+	}
+}
+
+// Sets how the shapes of upcoming tetrominos are picked. It's passed
+// the seed the game was created with. Games use a 7-bag by default
+func WithRandomizer(newRandomizer func(seed int64) Randomizer) GameOption {
+	return func(game *Game) {
+		game.newRandomizer = newRandomizer
+	}
 }
 
 const LINES_PER_LVL = 4
 const MAX_LEVEL = 20
 const DURATION_DIFF = 45 * time.Millisecond
 
-// Create a new game with a given random seed, and hook it to some
-// sort of movement channel to get inputs
-func NewGame(seed int64, level int) *Game {
-	var next *Tetromino
+// The default gravity, which speeds up by the same amount each level
+// to a minimum of 100ms between rows at level 20
+func linearGravity(level int) time.Duration {
+	return DEFAULT_DURATION - DURATION_DIFF*time.Duration(level)
+}
 
-	tets := TetFactory(seed)
-	firstTet := <-tets
-	next = <-tets
+// The default level progression, which goes up a level every
+// LINES_PER_LVL lines until MAX_LEVEL
+func linearLevel(startingLevel, lines int) int {
+	var lvl = (lines / LINES_PER_LVL) + startingLevel
+	if lvl > MAX_LEVEL {
+		return MAX_LEVEL
+	}
+	return lvl
+}
 
+// Create a new game with a given random seed, and hook it to some
+// sort of movement channel to get inputs
+func NewGame(seed int64, level int, opts ...GameOption) *Game {
 	game := &Game{
-		controller:    NewBoardController(&Board{}, firstTet),
-		nextTet:       next,
-		tetSource:     tets,
+		rotation:      ClassicRotation{},
+		rules:         ClassicRuleset(),
+		width:         BOARD_WIDTH,
+		visible:       GAMEOVER_LINE,
+		scorer:        ClassicScorer{},
+		lockDelay:     DEFAULT_LOCK_DELAY,
+		maxResets:     DEFAULT_LOCK_RESETS,
+		gravity:       linearGravity,
+		leveling:      linearLevel,
+		newRandomizer: NewSevenBagRandomizer,
+		clock:         RealClock,
+		frame:         DEFAULT_FRAME,
+		preview:       make([]*Tetromino, DEFAULT_PREVIEW),
 		startingLevel: level,
 	}
 
+	for _, opt := range opts {
+		opt(game)
+	}
+
+	game.seed = seed
+	game.randomizer = game.newRandomizer(seed)
+
+	firstTet := NewTet(game.deal())
+	for i := range game.preview {
+		game.preview[i] = game.pullTet()
+	}
+
+	// The board has the final say on it's size
+	board := NewBoard(game.width, game.visible)
+	game.width, game.visible = board.Width(), board.Visible()
+	game.controller = NewBoardControllerWithRules(board, firstTet, game.rotation, game.rules)
+
 	return game
 }
 
 func (game *Game) Level() int {
-	var lvl = (game.lines / LINES_PER_LVL) + game.startingLevel
-	if lvl > MAX_LEVEL {
-		return MAX_LEVEL
-	}
-	return lvl
+	return game.leveling(game.startingLevel, game.lines)
 }
 
 // Applies logic for clearing lines
@@ -435,117 +682,416 @@ func (game *Game) ClearLines(cleared int) {
 	game.lines += cleared
 }
 
-// Adds values to score based on the level, the number of lines
-// cleared, the time, etc. Should only be called once for gameover
-func (game *Game) CalcEndBonuses() int {
-	var score int
+// Fetches a tetromino from the randomizer. It's put in it's spawn
+// orientation right away, so previews show what will be played
+func (game *Game) pullTet() *Tetromino {
+	tet := NewTet(game.deal())
+	game.rotation.Spawn(tet)
+	return tet
+}
 
-	score += game.ticks
-	score += game.Level() * 10
-	if game.Level() == MAX_LEVEL {
-		score += 1000
-	}
-	return score
+// Gets the next shape from the randomizer, counting it so a saved game
+// can get back to the same place
+func (game *Game) deal() Shape {
+	game.dealt++
+	return game.randomizer.Next()
 }
 
-// Fetches the next tetromino from internal source
+// Advances the preview queue. The tetromino at the front is dropped,
+// and a new one from the randomizer is added to the back
 func (game *Game) NextTet() {
-	game.nextTet = <-game.tetSource
+	copy(game.preview, game.preview[1:])
+	game.preview[len(game.preview)-1] = game.pullTet()
 }
 
-// Calculates a score that's meant to be applied between ordinairy non
-// tetris ticks. It should only take the level and time into account
-func (game *Game) CalcTickScore() int {
-	return game.ticks * game.Level()
+// Puts the active tetromino aside, and brings back the one that was
+// held before. If nothing has been held yet, the next tetromino is
+// brought in instead. Does nothing if a hold was already used on the
+// active tetromino.
+func (game *Game) Hold() {
+	if game.holdUsed {
+		return
+	}
+
+	if game.heldTet == nil {
+		game.heldTet = game.controller.Swap(game.preview[0])
+		game.NextTet()
+	} else {
+		game.heldTet = game.controller.Swap(game.heldTet)
+	}
+
+	game.holdUsed = true
 }
 
-func (game *Game) Tick(move Movement) {
+// Applies a move to the game. Moves that aren't valid are rejected
+// with ErrInvalidMove, and the game is left exactly as it was.
+func (game *Game) Tick(move Movement) error {
+	if !move.Valid() {
+		return fmt.Errorf("%w: movement %v", ErrInvalidMove, move)
+	}
+
+	if game.phase != PHASE_ACTIVE {
+		// There's nothing to move, hold onto it for the next tetromino
+		game.buffered = append(game.buffered, move)
+		return nil
+	}
+
 	game.ticks++ // Keeps track of the number of turns
 
-	// Apply move to the board, get the number of lines
-	cleared, consumed := game.controller.Tick(move, game.nextTet)
+	var result TickResult
+	prev := game.controller.tet.Tetromino
 
-	if consumed {
-		game.NextTet()
+	// When there's a delay after locking, the next tetromino is left
+	// where it is until it's over
+	next := game.preview[0]
+	if game.clearTimer != nil || game.entryTimer != nil {
+		next = nil
 	}
 
-	if cleared > 0 {
-		// Tetris must have occurred
-		game.ClearLines(cleared)
-	} else if !game.controller.isGameover {
-		game.score += game.CalcTickScore()
+	if move == MOVE_HOLD {
+		// Holding never touches the board controller's queue, the game
+		// decides what comes in next
+		game.Hold()
 	} else {
-		// Game is over
-		game.score += game.CalcEndBonuses()
+		// Apply move to the board, get the number of lines
+		result = game.controller.Tick(move, next)
+	}
+
+	if result.Consumed {
+		game.holdUsed = false
+		if next != nil {
+			game.NextTet()
+		} else {
+			game.startDelay(result.Rows)
+		}
+	}
+
+	game.updateLockDelay(result.Moved, result.Consumed || game.controller.tet.Tetromino != prev)
+
+	// Score before counting the lines, so they're worth the level they
+	// were cleared on
+	event := ScoreEvent{
+		Locked:       result.Consumed,
+		Lines:        result.Lines,
+		TSpin:        result.TSpin,
+		PerfectClear: result.PerfectClear,
+		HardDrop:     result.Dropped,
+		Level:        game.Level(),
+		Ticks:        game.ticks,
+		Gameover:     game.controller.isGameover,
+	}
+	if move == MOVE_DOWN && result.Moved {
+		event.SoftDrop = 1
+	}
+	game.score += game.scorer.Score(event)
+
+	if result.Lines > 0 {
+		game.ClearLines(result.Lines)
+	}
+
+	return nil
+}
+
+// Arms, restarts or stops the lock timer depending on what just
+// happened to the active tetromino. The delay starts when it lands,
+// and each successful move or rotation on the ground restarts it until
+// the resets are used up. Does nothing if there's no lock timer, which
+// is the case outside of Play.
+func (game *Game) updateLockDelay(moved, newTet bool) {
+	if game.lockTimer == nil {
+		return
+	}
+
+	if newTet {
+		game.lockTimer.Stop()
+		game.lockArmed = false
+		game.lockResets = 0
+	}
+
+	if game.phase != PHASE_ACTIVE {
+		return
+	}
+
+	switch {
+	case game.controller.CanMoveDown():
+		if game.lockArmed {
+			game.lockTimer.Stop()
+			game.lockArmed = false
+		}
+	case !game.lockArmed:
+		game.lockTimer.Reset()
+		game.lockArmed = true
+	case moved && game.lockResets < game.maxResets:
+		game.lockTimer.Reset()
+		game.lockResets++
 	}
 }
 
 // A value that represents a point in time for a given game. This can
 // be produced by a game, and sent to something else to draw it or
-// something else. It's intentionally a single large value
+// something else. It's intentionally a single large value. Encoding it
+// as JSON gives a stable form for tools outside of the game, see
+// MarshalJSON.
 type GameSnapshot struct {
-	Score      int
-	Level      int
-	Ticks      int
+	Score int
+	Level int
+	Ticks int
+	// Number of frames the game has been stepped, if it's stepped
+	Frame int
+	// Goes up each time a tetromino comes into play, so a new one can
+	// be told apart from the last even if it's in the same place
+	Piece int
+	// How long it takes the current tetromino to fall a row
+	Gravity time.Duration
+	// What the game is doing. Outside of PHASE_ACTIVE nothing is in
+	// play, and CurrentTet is the tetromino that was last locked
+	Phase Phase
+	// The full rows during PHASE_LINE_CLEAR, counting from the bottom.
+	// They're still on the board until the phase is over
+	Clearing []int
+	// Only the tiles that have been locked in place. Use View to get
+	// the board with the current tetromino on it as well
 	Board      Board
 	CurrentTet Tetromino
-	NextTet    Tetromino
-	Position   Position
+	// The same as the first tetromino in the preview
+	NextTet Tetromino
+	// Every upcoming tetromino the player can see, in order
+	Preview []Tetromino
+	// A copy of the held tetromino, or nil if nothing is held
+	HeldTet  *Tetromino
+	Position Position
+	// Where the current tetromino would land if it were slammed, or nil
+	// if nothing is in play
+	Ghost []Position
 }
 
 func (game *Game) Snap() GameSnapshot {
-	return GameSnapshot{
+	snap := GameSnapshot{
 		Score:      game.score,
 		Level:      game.Level(),
 		Ticks:      game.ticks,
-		Board:      *game.controller.board,
+		Frame:      game.steps.frame,
+		Piece:      game.controller.spawned,
+		Gravity:    game.gravity(game.Level()),
+		Phase:      game.phase,
+		Board:      game.controller.board.Clone(),
 		CurrentTet: *game.controller.tet.Tetromino,
-		NextTet:    *game.nextTet,
+		NextTet:    *game.preview[0],
+		Preview:    make([]Tetromino, len(game.preview)),
 		Position:   game.controller.tet.Position,
 	}
+
+	if game.phase == PHASE_ACTIVE {
+		snap.Ghost = game.controller.Ghost()
+	}
+
+	if game.clearing != nil {
+		snap.Clearing = append([]int(nil), game.clearing...)
+	}
+
+	for i, tet := range game.preview {
+		snap.Preview[i] = *tet
+	}
+
+	if game.heldTet != nil {
+		held := *game.heldTet
+		snap.HeldTet = &held
+	}
+
+	return snap
+}
+
+// Returns the board as the player sees it, with the current tetromino
+// drawn on top of the locked tiles if it's in play
+func (snap GameSnapshot) View() Board {
+	view := snap.Board.Clone()
+	if snap.Phase == PHASE_ACTIVE {
+		ActiveTetromino{&snap.CurrentTet, snap.Position}.stamp(&view)
+	}
+	return view
+}
+
+// Why a game stopped being played
+type EndReason int
+
+const (
+	// The stack reached the top of the board
+	END_TOPPED_OUT EndReason = iota
+	// The context passed to Play was cancelled
+	END_CANCELLED
+	// The channel of movements was closed
+	END_INPUT_CLOSED
+)
+
+func (reason EndReason) String() string {
+	switch reason {
+	case END_TOPPED_OUT:
+		return "topped out"
+	case END_CANCELLED:
+		return "cancelled"
+	case END_INPUT_CLOSED:
+		return "input closed"
+	default:
+		return "unknown"
+	}
+}
+
+// The outcome of a game once Play is done with it
+type GameResult struct {
+	Score  int
+	Lines  int
+	Level  int
+	Ticks  int
+	Reason EndReason
+}
+
+func (game *Game) result(reason EndReason) GameResult {
+	return GameResult{
+		Score:  game.score,
+		Lines:  game.lines,
+		Level:  game.Level(),
+		Ticks:  game.ticks,
+		Reason: reason,
+	}
 }
 
 // Listens for incoming movements on a channel and applies them until
-// the game is over. If called with the debug flag then the timer is
-// disabled and movement is simply free form
-func (game *Game) Play(moves <-chan Movement, snaps chan<- GameSnapshot, debug bool) {
+// the game is over, the context is cancelled, or the movements channel
+// is closed. A snapshot is sent after every movement, and the
+// snapshots channel is closed once Play returns. If called with the
+// debug flag then the timers are disabled and movement is simply free
+// form
+func (game *Game) Play(ctx context.Context, moves <-chan Movement, snaps chan<- GameSnapshot, debug bool) GameResult {
+	defer close(snaps)
+
+	// Sends a snapshot unless the context is cancelled while waiting
+	// for it to be received
+	send := func() bool {
+		select {
+		case snaps <- game.Snap():
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
 	if debug {
+		game.resumeDelay()
 		for !game.controller.isGameover {
-			for move := range moves {
+			select {
+			case <-ctx.Done():
+				return game.result(END_CANCELLED)
+			case move, ok := <-moves:
+				if !ok {
+					return game.result(END_INPUT_CLOSED)
+				}
+
 				game.Tick(move)
-				snaps <- game.Snap()
+				if !send() {
+					return game.result(END_CANCELLED)
+				}
 			}
 		}
-	} else {
-		timer := NewResetTimer(DEFAULT_DURATION)
 
-		var move Movement
-		for !game.controller.isGameover {
-			// Update the timer duration, this will progressively
-			// speed the game up to a minimum of 50ms between moves at
-			// lvl 20
-			timer.duration = DEFAULT_DURATION - DURATION_DIFF*time.Duration(game.Level())
+		return game.result(END_TOPPED_OUT)
+	}
 
-			select {
-			case <-timer.out:
-				game.Tick(MOVE_FORCE_DOWN)
-				snaps <- game.Snap()
+	timer := NewResetTimerWithClock(game.clock, game.gravity(game.Level()))
+	defer timer.Close()
+
+	var delays []*ResetTimer
+	defer func() {
+		for _, delay := range delays {
+			delay.Close()
+		}
+		game.lockTimer, game.clearTimer, game.entryTimer = nil, nil, nil
+	}()
+
+	// The delays only run when the game starts them, the lock timer
+	// while the tetromino is on the ground and the others after it
+	// locks. A delay of zero has no timer, and it's channel is never
+	// ready
+	newDelay := func(d time.Duration, t *delayTimer) <-chan struct{} {
+		if d <= 0 {
+			return nil
+		}
+
+		delay := NewResetTimerWithClock(game.clock, d)
+		delay.Stop()
+		delays = append(delays, delay)
+		*t = delay
+		return delay.out
+	}
+
+	lockOut := newDelay(game.lockDelay, &game.lockTimer)
+	clearOut := newDelay(game.lineClearDelay, &game.clearTimer)
+	entryOut := newDelay(game.entryDelay, &game.entryTimer)
+	game.resumeDelay()
+
+	var move Movement
+	for !game.controller.isGameover {
+		// Update the timer duration, this will progressively speed the
+		// game up as the level goes up
+		timer.SetDuration(game.gravity(game.Level()))
+
+		select {
+		case <-ctx.Done():
+			return game.result(END_CANCELLED)
+		case <-timer.out:
+			// Gravity has nothing to do once the tetromino has landed,
+			// it's up to the lock delay from then on. Nothing falls
+			// between tetrominos either
+			if game.phase != PHASE_ACTIVE ||
+				game.lockTimer != nil && !game.controller.CanMoveDown() {
 				continue
-			default:
 			}
-
-			select {
-			case <-timer.out:
-				move = MOVE_FORCE_DOWN
-			case move = <-moves:
-				if game.controller.CanMoveDown() && move == MOVE_DOWN || move == MOVE_SLAM {
-					timer.Reset()
-				}
+			move = MOVE_FORCE_DOWN
+		case <-clearOut:
+			if game.phase != PHASE_LINE_CLEAR {
+				continue
+			}
+			game.finishDelay()
+			// Gravity starts over for the next tetromino, if there's
+			// no entry delay holding it back
+			if game.phase == PHASE_ACTIVE {
+				timer.Reset()
+			}
+			if !send() {
+				return game.result(END_CANCELLED)
+			}
+			continue
+		case <-entryOut:
+			if game.phase != PHASE_ENTRY {
+				continue
+			}
+			game.finishDelay()
+			timer.Reset()
+			if !send() {
+				return game.result(END_CANCELLED)
+			}
+			continue
+		case <-lockOut:
+			// Forcing down a tetromino that's on the ground locks it
+			if game.controller.CanMoveDown() {
+				continue
+			}
+			move = MOVE_FORCE_DOWN
+		case m, ok := <-moves:
+			if !ok {
+				return game.result(END_INPUT_CLOSED)
 			}
 
-			game.Tick(move)
-			snaps <- game.Snap()
+			move = m
+			if game.controller.CanMoveDown() && move == MOVE_DOWN || move == MOVE_SLAM {
+				timer.Reset()
+			}
+		}
 
+		game.Tick(move)
+		if !send() {
+			return game.result(END_CANCELLED)
 		}
 	}
+
+	return game.result(END_TOPPED_OUT)
 }
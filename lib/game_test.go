@@ -1,8 +1,12 @@
 package lib
 
 import (
+	"context"
+	"errors"
 	"math/rand"
+	"reflect"
 	"testing"
+	"time"
 )
 
 func TestActiveTetrominoMove(t *testing.T) {
@@ -210,25 +214,30 @@ func TestCanMove(t *testing.T) {
 func TestBoardControllerNextTet(t *testing.T) {
 	board := &Board{}
 
-	ctl := NewBoardController(board, NewTet(TET_LINE))
+	ctl := NewBoardController(board, NewTet(TET_LINE), ClassicRotation{})
 
 	// Check that the active tet has a line shape
 	if ctl.tet.shape != TET_LINE {
 		t.Error("Tetromino has non line shape")
 	}
 
-	// Check that none of the tiles are empty, they should have some value
+	// The active tetromino isn't locked yet, so it shouldn't be on
+	// the board itself, only in the view of it
+	view := ctl.View()
 	for _, p := range ctl.tet.ListPositions() {
-		if ctl.board.IsEmpty(p.x, p.y) {
+		if !ctl.board.IsEmpty(p.x, p.y) {
+			t.Errorf("Active tetromino found on the board: %v", p)
+		}
+		if view.IsEmpty(p.x, p.y) {
 			t.Errorf("Unexpected empty tile: %v", p)
 		}
 	}
 
 	// Look at the positions of the tetromino and compare that to the
-	// board. If these aren't set, we've got a problem
+	// view. If these aren't set, we've got a problem
 	expectedTC := ShapeToTC(ctl.tet.shape)
 	for _, p := range ctl.tet.ListPositions() {
-		if tc := ctl.board.GetTile(p.x, p.y); tc != expectedTC {
+		if tc := view.GetTile(p.x, p.y); tc != expectedTC {
 			t.Errorf("Incorrect TileColor: expected %v, found %v", expectedTC, tc)
 		}
 	}
@@ -237,17 +246,25 @@ func TestBoardControllerNextTet(t *testing.T) {
 	ctl.Slam()
 	ctl.NextTet(NewTet(TET_SQUARE))
 
-	// Check that there are now a total of 8 tiles in the board
-	var tileCount int
-	for _, tc := range ctl.board.tiles {
-		if tc != EMPTY {
-			tileCount++
+	// Check that there are now a total of 8 tiles in view, 4 of
+	// which are locked to the board
+	countTiles := func(b Board) int {
+		var tileCount int
+		for _, tc := range b.tiles {
+			if tc != EMPTY {
+				tileCount++
+			}
 		}
+		return tileCount
 	}
 
-	if tileCount != 8 {
+	if countTiles(ctl.View()) != 8 {
 		t.Error("Unexpected number of tiles found")
 	}
+
+	if countTiles(*ctl.board) != 4 {
+		t.Error("Unexpected number of locked tiles found")
+	}
 }
 
 func TestBoardControllerMove(t *testing.T) {
@@ -259,7 +276,7 @@ func TestBoardControllerMove(t *testing.T) {
 	trial := func() {
 		board := &Board{}
 
-		ctl := NewBoardController(board, NewTet(TET_LINE))
+		ctl := NewBoardController(board, NewTet(TET_LINE), ClassicRotation{})
 
 		// Move randomly 100 times. If our movement code is safe, then it
 		// should end up just fine without crashing. It's also highly
@@ -308,7 +325,7 @@ func TestSlam(t *testing.T) {
 	for _, test := range tests {
 		// Setup the board controller
 		board := &Board{}
-		ctl := NewBoardController(board, NewTet(test.shape))
+		ctl := NewBoardController(board, NewTet(test.shape), ClassicRotation{})
 
 		for _, p := range test.boardPositions {
 			ctl.board.SetTile(ShapeToTC(test.shape), p.x, p.y)
@@ -334,7 +351,7 @@ func TestSlam(t *testing.T) {
 // Ensure the tetris effect is applied when NextTet is called
 func TestNextTetTetris(t *testing.T) {
 	board := &Board{}
-	ctl := NewBoardController(board, NewTet(TET_T))
+	ctl := NewBoardController(board, NewTet(TET_T), ClassicRotation{})
 
 	// Set all but one tile in the bottom of a board to non empty
 	for x := 0; x < BOARD_WIDTH; x++ {
@@ -350,9 +367,10 @@ func TestNextTetTetris(t *testing.T) {
 	// There should only be 3 tiles in the entire board
 	var tileCount int
 	var tile TileColor
+	view := ctl.View()
 	for y := 0; y < BOARD_HEIGHT; y++ {
 		for x := 0; x < BOARD_WIDTH; x++ {
-			tile = board.GetTile(x, y)
+			tile = view.GetTile(x, y)
 			if tile != EMPTY {
 				t.Logf("Tile with color %v found at postion (%v, %v)", tile, x, y)
 				tileCount++
@@ -381,7 +399,7 @@ func TestNextTetTetris(t *testing.T) {
 func TestBoardControllerRotation(t *testing.T) {
 	// Setup
 	board := &Board{}
-	ctl := NewBoardController(board, NewTet(TET_LINE))
+	ctl := NewBoardController(board, NewTet(TET_LINE), ClassicRotation{})
 
 	// Move our line peice all the way to the right, as far as it will
 	// go
@@ -436,7 +454,7 @@ func TestRandomWalkStressTest(t *testing.T) {
 	}
 	// We need to queue up an extra shape, or we'll deadlock
 	source <- NewTet(TET_SQUARE)
-	ctl := NewBoardController(board, NewTet(TET_SQUARE))
+	ctl := NewBoardController(board, NewTet(TET_SQUARE), ClassicRotation{})
 
 	const MOVEMENTS = 200
 
@@ -469,7 +487,7 @@ func TestGameOver(t *testing.T) {
 	// Setup
 	board := &Board{}
 	// We need to queue up an extra shape, or we'll deadlock
-	ctl := NewBoardController(board, NewTet(TET_SQUARE))
+	ctl := NewBoardController(board, NewTet(TET_SQUARE), ClassicRotation{})
 
 	if ctl.isGameover {
 		t.Error("Gameover signal detected too early")
@@ -485,7 +503,7 @@ func TestGameOver(t *testing.T) {
 func TestNaturalGameover(t *testing.T) {
 	// Setup
 	board := &Board{}
-	ctl := NewBoardController(board, NewTet(TET_LINE))
+	ctl := NewBoardController(board, NewTet(TET_LINE), ClassicRotation{})
 
 	for i := 0; i < 4; i++ {
 		ctl.Slam()
@@ -506,7 +524,7 @@ func TestNaturalGameover(t *testing.T) {
 
 func TestBoardForceTick(t *testing.T) {
 	board := &Board{}
-	ctl := NewBoardController(board, NewTet(TET_LINE))
+	ctl := NewBoardController(board, NewTet(TET_LINE), ClassicRotation{})
 
 	// If we send force down movements, (simulates doing nothing), the
 	// game should eventually end
@@ -542,16 +560,16 @@ func TestGameClearLines(t *testing.T) {
 func TestNextTet(t *testing.T) {
 	game := NewGame(0, 1)
 
-	tet := game.nextTet
+	tet := game.preview[0]
 
 	for i := 0; i < 10; i++ {
 		game.NextTet()
 
-		if tet == game.nextTet {
+		if tet == game.preview[0] {
 			t.Error("Next tetromino did not change")
 		}
 
-		tet = game.nextTet
+		tet = game.preview[0]
 	}
 }
 
@@ -575,8 +593,8 @@ func TestGameRandom(t *testing.T) {
 			game.Tick(move)
 		}
 
-		tet := game.nextTet
-		for tet == game.nextTet {
+		tet := game.preview[0]
+		for tet == game.preview[0] {
 			game.Tick(MOVE_FORCE_DOWN)
 		}
 	}
@@ -605,9 +623,505 @@ func TestGameListen(t *testing.T) {
 		}
 	}()
 
-	game.Play(slamChan, snapChan, false)
+	result := game.Play(context.Background(), slamChan, snapChan, false)
 
 	if !game.controller.isGameover {
 		t.Error("Expected gameover, but game is still active")
 	}
+
+	if result.Reason != END_TOPPED_OUT || result.Score != game.score || result.Ticks != game.ticks {
+		t.Errorf("Result doesn't match the finished game: %+v", result)
+	}
+}
+
+func TestGamePlayCancel(t *testing.T) {
+	game := NewGame(0, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Cancel after the first snapshot, without reading any more
+	snapChan := make(chan GameSnapshot)
+	done := make(chan struct{})
+	go func() {
+		<-snapChan
+		cancel()
+
+		// Play has to close the channel once it's returned
+		for range snapChan {
+		}
+		close(done)
+	}()
+
+	moves := make(chan Movement, 1)
+	moves <- MOVE_LEFT
+
+	result := game.Play(ctx, moves, snapChan, false)
+	<-done
+
+	if result.Reason != END_CANCELLED {
+		t.Errorf("Expected the game to be cancelled, found %v", result.Reason)
+	}
+
+	if game.lockTimer != nil {
+		t.Error("Lock timer is still around after Play returned")
+	}
+}
+
+func TestGamePlayInputClosed(t *testing.T) {
+	game := NewGame(0, 1)
+
+	moves := make(chan Movement, 2)
+	moves <- MOVE_SLAM
+	moves <- MOVE_SLAM
+	close(moves)
+
+	snapChan := make(chan GameSnapshot, 2)
+	result := game.Play(context.Background(), moves, snapChan, true)
+
+	if result.Reason != END_INPUT_CLOSED || result.Ticks != 2 {
+		t.Errorf("Expected the game to end after two moves, found %+v", result)
+	}
+
+	var snapCount int
+	for range snapChan {
+		snapCount++
+	}
+
+	if snapCount != 2 {
+		t.Errorf("Expected a snapshot for each move, found %v", snapCount)
+	}
+}
+
+func TestBoardControllerSwap(t *testing.T) {
+	board := &Board{}
+	ctl := NewBoardController(board, NewTet(TET_LINE), ClassicRotation{})
+
+	// Move it out of the way, so we know the swap puts the new one
+	// back at the top
+	ctl.Slam()
+
+	prev := ctl.Swap(NewTet(TET_SQUARE))
+
+	if prev.shape != TET_LINE {
+		t.Errorf("Swap returned the wrong tetromino, found shape %v", prev.shape)
+	}
+
+	if ctl.tet.shape != TET_SQUARE || ctl.tet.Position != (Position{STARTING_X, STARTING_Y}) {
+		t.Errorf("Swapped in tetromino isn't at the top. Shape %v at %v", ctl.tet.shape, ctl.tet.Position)
+	}
+
+	// Only the tiles for the square should be left in view
+	var tileCount int
+	for _, tc := range ctl.View().tiles {
+		if tc != EMPTY {
+			tileCount++
+		}
+	}
+
+	if tileCount != 4 {
+		t.Errorf("Swapped out tetromino left tiles behind. Found %v tiles", tileCount)
+	}
+}
+
+// The board should only hold locked tiles, so a full line that's
+// only completed by the falling tetromino isn't really full yet
+func TestActiveTetNotOnBoard(t *testing.T) {
+	board := &Board{}
+	ctl := NewBoardController(board, NewTet(TET_LINE), ClassicRotation{})
+
+	// Fill the bottom row, except where the line will land
+	for x := 0; x < BOARD_WIDTH; x++ {
+		if x != STARTING_X+1 {
+			board.SetTile(C1, x, 0)
+		}
+	}
+
+	ctl.Slam()
+
+	if lines := board.FullLines(); len(lines) != 0 {
+		t.Errorf("Falling tetromino counted towards full lines: %v", lines)
+	}
+
+	view := ctl.View()
+	if lines := view.FullLines(); len(lines) != 1 {
+		t.Errorf("Expected the view to have 1 full line, found %v", lines)
+	}
+
+	if cleared := ctl.NextTet(NewTet(TET_LINE)); cleared != 1 {
+		t.Errorf("Expected locking to clear 1 line, found %v", cleared)
+	}
+
+	snap := GameSnapshot{Board: *board, CurrentTet: *ctl.tet.Tetromino, Position: ctl.tet.Position}
+	if view, expected := snap.View(), ctl.View(); !view.Equal(&expected) {
+		t.Error("Snapshot view doesn't match the controller's view")
+	}
+}
+
+func TestGameHold(t *testing.T) {
+	game := NewGame(0, 1)
+
+	first := game.controller.tet.Tetromino
+	second := game.preview[0]
+
+	// With nothing held yet, the next tetromino should come in
+	game.Tick(MOVE_HOLD)
+
+	if game.heldTet != first {
+		t.Error("Active tetromino wasn't held")
+	}
+	if game.controller.tet.Tetromino != second {
+		t.Error("Next tetromino wasn't brought in after holding")
+	}
+	if game.preview[0] == second {
+		t.Error("Next tetromino wasn't replaced after holding")
+	}
+
+	// Can't hold twice in a row
+	game.Tick(MOVE_HOLD)
+
+	if game.heldTet != first || game.controller.tet.Tetromino != second {
+		t.Error("Hold was used twice on the same tetromino")
+	}
+
+	if snap := game.Snap(); snap.HeldTet == nil || snap.HeldTet.shape != first.shape {
+		t.Error("Snapshot doesn't contain the held tetromino")
+	}
+
+	// Lock the tetromino in place, so holding is allowed again
+	game.Tick(MOVE_SLAM)
+
+	third := game.controller.tet.Tetromino
+	game.Tick(MOVE_HOLD)
+
+	if game.heldTet != third || game.controller.tet.Tetromino != first {
+		t.Error("Held tetromino wasn't swapped back in after locking")
+	}
+}
+
+func TestGamePreview(t *testing.T) {
+	game := NewGame(0, 1, WithPreview(5))
+
+	if len(game.preview) != 5 {
+		t.Fatalf("Expected a preview of 5 tetrominos, found %v", len(game.preview))
+	}
+
+	upcoming := make([]*Tetromino, len(game.preview))
+	copy(upcoming, game.preview)
+
+	// Lock the active tetromino, everything should shuffle forward
+	game.Tick(MOVE_SLAM)
+
+	if game.controller.tet.Tetromino != upcoming[0] {
+		t.Error("Tetromino at the front of the preview wasn't played next")
+	}
+
+	for i := 0; i < len(upcoming)-1; i++ {
+		if game.preview[i] != upcoming[i+1] {
+			t.Errorf("Preview is out of order at index %v", i)
+		}
+	}
+
+	snap := game.Snap()
+	if len(snap.Preview) != 5 || snap.Preview[0].shape != game.preview[0].shape {
+		t.Error("Snapshot doesn't match the preview")
+	}
+
+	// Depths outside of the supported range are clamped
+	if depth := len(NewGame(0, 1, WithPreview(0)).preview); depth != MIN_PREVIEW {
+		t.Errorf("Expected preview depth %v, found %v", MIN_PREVIEW, depth)
+	}
+	if depth := len(NewGame(0, 1, WithPreview(100)).preview); depth != MAX_PREVIEW {
+		t.Errorf("Expected preview depth %v, found %v", MAX_PREVIEW, depth)
+	}
+}
+
+func TestGhost(t *testing.T) {
+	board := &Board{}
+	ctl := NewBoardController(board, NewTet(TET_T), ClassicRotation{})
+
+	for x := 2; x < 8; x++ {
+		board.SetTile(C1, x, 3)
+	}
+
+	before := board.Clone()
+	ghost := ctl.Ghost()
+
+	if !board.Equal(&before) {
+		t.Error("Computing the ghost changed the board")
+	}
+
+	ctl.Slam()
+
+	if !reflect.DeepEqual(ghost, ctl.tet.ListPositions()) {
+		t.Errorf("Ghost doesn't match where the tetromino landed. Expected %v, found %v",
+			ctl.tet.ListPositions(), ghost)
+	}
+
+	// Once it's landed, the ghost is right where the tetromino is
+	if !reflect.DeepEqual(ctl.Ghost(), ctl.tet.ListPositions()) {
+		t.Error("Ghost moved away from a tetromino that can't fall")
+	}
+}
+
+func TestBoardControllerHardDrop(t *testing.T) {
+	board := &Board{}
+	ctl := NewBoardController(board, NewTet(TET_LINE), ClassicRotation{})
+
+	result := ctl.Tick(MOVE_SLAM, NewTet(TET_SQUARE))
+
+	if !result.Consumed || ctl.tet.shape != TET_SQUARE {
+		t.Error("Hard drop didn't lock the tetromino right away")
+	}
+
+	if result.Moved {
+		t.Error("Hard drop reported a move, even though the tetromino locked")
+	}
+}
+
+func TestGameLockDelay(t *testing.T) {
+	const RESETS = 3
+	game := NewGame(0, 1, WithLockDelay(time.Hour, RESETS))
+
+	// A timer that will never fire on it's own, so we can look at how
+	// the game arms it without racing against it
+	game.lockTimer = NewResetTimer(time.Hour)
+	game.lockTimer.Stop()
+
+	tet := game.controller.tet.Tetromino
+	for game.controller.CanMoveDown() {
+		game.Tick(MOVE_DOWN)
+	}
+
+	if !game.lockArmed {
+		t.Fatal("Lock delay didn't start when the tetromino landed")
+	}
+
+	// Forcing down would normally lock, but soft dropping on the
+	// ground does nothing at all
+	game.Tick(MOVE_DOWN)
+	if game.controller.tet.Tetromino != tet {
+		t.Fatal("Soft drop locked the tetromino")
+	}
+
+	// Moving back and forth restarts the delay, up to the limit
+	for i := 0; i < RESETS*2; i++ {
+		if i%2 == 0 {
+			game.Tick(MOVE_LEFT)
+		} else {
+			game.Tick(MOVE_RIGHT)
+		}
+	}
+
+	if game.lockResets != RESETS {
+		t.Errorf("Expected %v lock resets to be used, found %v", RESETS, game.lockResets)
+	}
+
+	// Locking brings in a new tetromino, which is back in the air
+	game.Tick(MOVE_FORCE_DOWN)
+	if game.controller.tet.Tetromino == tet {
+		t.Fatal("Forcing down on the ground didn't lock the tetromino")
+	}
+
+	if game.lockArmed || game.lockResets != 0 {
+		t.Error("Lock delay wasn't cleared for the new tetromino")
+	}
+}
+
+// Waits for the next snapshot from Play. Everything it does in these
+// tests is set off by the test itself, so it never takes long
+func nextSnap(t *testing.T, snaps <-chan GameSnapshot) GameSnapshot {
+	t.Helper()
+
+	select {
+	case snap := <-snaps:
+		return snap
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for a snapshot")
+		return GameSnapshot{}
+	}
+}
+
+// Starts playing a game on a fake clock in the background. The
+// returned function stops the game and waits for Play to return
+func playFake(t *testing.T, game *Game) (chan<- Movement, <-chan GameSnapshot, func()) {
+	moves := make(chan Movement)
+	snaps := make(chan GameSnapshot)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		game.Play(ctx, moves, snaps, false)
+		close(done)
+	}()
+
+	// Play has set up it's timers once it's handled a move
+	moves <- MOVE_LEFT
+	nextSnap(t, snaps)
+
+	return moves, snaps, func() {
+		cancel()
+		for range snaps {
+		}
+		<-done
+	}
+}
+
+func TestGamePlayGravity(t *testing.T) {
+	for _, level := range []int{1, 10, 20} {
+		clock := NewFakeClock(time.Time{})
+		game := NewGame(0, level, WithClock(clock))
+		moves, snaps, stop := playFake(t, game)
+
+		gravity := linearGravity(level)
+		for row := 0; row < 3; row++ {
+			y := game.controller.tet.y
+
+			// Nothing happens right before gravity is due. A move to
+			// the side gets a snapshot out of Play to show that
+			clock.Advance(gravity - time.Nanosecond)
+			moves <- []Movement{MOVE_RIGHT, MOVE_LEFT}[row%2]
+			if snap := nextSnap(t, snaps); snap.Position.y != y {
+				t.Fatalf("Level %v: tetromino fell before %v", level, gravity)
+			}
+
+			clock.Advance(time.Nanosecond)
+			if snap := nextSnap(t, snaps); snap.Position.y != y-1 {
+				t.Fatalf("Level %v: tetromino didn't fall after %v", level, gravity)
+			}
+		}
+
+		stop()
+	}
+}
+
+func TestGamePlayLockDelay(t *testing.T) {
+	clock := NewFakeClock(time.Time{})
+	game := NewGame(0, 1, WithClock(clock))
+	moves, snaps, stop := playFake(t, game)
+	defer stop()
+
+	// Soft drop until the tetromino lands, which arms the lock delay.
+	// Only the snapshots are looked at, since Play owns the game
+	landed := func(snap GameSnapshot) bool {
+		return reflect.DeepEqual(ActiveTetromino{&snap.CurrentTet, snap.Position}.ListPositions(), snap.Ghost)
+	}
+	for {
+		moves <- MOVE_DOWN
+		if landed(nextSnap(t, snaps)) {
+			break
+		}
+	}
+
+	// Moving on the ground restarts the delay
+	clock.Advance(DEFAULT_LOCK_DELAY - time.Nanosecond)
+	moves <- MOVE_LEFT
+	nextSnap(t, snaps)
+
+	clock.Advance(DEFAULT_LOCK_DELAY - time.Nanosecond)
+	moves <- MOVE_RIGHT
+	if !landed(nextSnap(t, snaps)) {
+		t.Fatal("Tetromino locked before the delay was up")
+	}
+
+	// Once it locks, the next one comes in up at the top
+	clock.Advance(DEFAULT_LOCK_DELAY)
+	if landed(nextSnap(t, snaps)) {
+		t.Error("Tetromino didn't lock once the delay was up")
+	}
+}
+
+func TestGameBoardSize(t *testing.T) {
+	for _, size := range [][2]int{{4, 8}, {12, 20}, {10, 30}} {
+		width, visible := size[0], size[1]
+
+		for name, rules := range Rulesets {
+			game := NewGame(0, 1, WithBoardSize(width, visible), WithRuleset(rules))
+
+			snap := game.Snap()
+			if snap.Board.Width() != width || snap.Board.Visible() != visible {
+				t.Errorf("%v %vx%v: snapshot board is %vx%v", name, width, visible,
+					snap.Board.Width(), snap.Board.Visible())
+			}
+
+			// Tetrominos come in just above the visible rows
+			_, _, bottom, _ := bounds(game.controller.tet.ListPositions())
+			if name == "guideline" && bottom != visible {
+				t.Errorf("%v %vx%v: expected to spawn in row %v, found %v", name, width, visible, visible, bottom)
+			}
+
+			// Stacking in the middle tops out without going off the board
+			for i := 0; i < 1000 && !game.IsGameover(); i++ {
+				game.Tick(MOVE_SLAM)
+			}
+			if !game.IsGameover() {
+				t.Errorf("%v %vx%v: expected to top out", name, width, visible)
+			}
+		}
+	}
+}
+
+// Games made without options play like the game always has
+func TestNewGameDefaults(t *testing.T) {
+	game := NewGame(0, 1)
+	if _, ok := game.rotation.(ClassicRotation); !ok {
+		t.Errorf("Expected the classic rotation system, found %T", game.rotation)
+	}
+	if _, ok := game.scorer.(ClassicScorer); !ok {
+		t.Errorf("Expected classic scoring, found %T", game.scorer)
+	}
+	if !reflect.DeepEqual(game.rules, ClassicRuleset()) {
+		t.Errorf("Expected the classic rules, found %+v", game.rules)
+	}
+}
+
+func TestGameBoardSizeLimits(t *testing.T) {
+	game := NewGame(0, 1, WithBoardSize(2, 2))
+	if game.width != MIN_BOARD_WIDTH || game.visible != MIN_BOARD_VISIBLE {
+		t.Errorf("Expected the game to be %vx%v, found %vx%v", MIN_BOARD_WIDTH, MIN_BOARD_VISIBLE, game.width, game.visible)
+	}
+	if board := game.controller.board; board.Width() != game.width || board.Visible() != game.visible {
+		t.Errorf("Expected the board to be the game's size, found %vx%v", board.Width(), board.Visible())
+	}
+}
+
+func TestActiveTetTryMove(t *testing.T) {
+	tet := NewActiveTet(NewTet(TET_T))
+
+	if moved, err := tet.TryMove(LEFT); err != nil || moved.x != tet.x-1 {
+		t.Errorf("Expected to move left, found %v, %v", moved.Position, err)
+	}
+	if _, err := tet.TryMove(Direction(4)); !errors.Is(err, ErrInvalidMove) {
+		t.Errorf("Expected ErrInvalidMove, found %v", err)
+	}
+	if tet.CanMove(Direction(-1), &Board{}) {
+		t.Error("Expected to be unable to move in a direction that doesn't exist")
+	}
+}
+
+func TestGameTickInvalidMove(t *testing.T) {
+	game := NewGame(0, 1)
+	before := game.Snap()
+
+	for _, move := range []Movement{-1, maxMovement + 1, 100} {
+		if err := game.Tick(move); !errors.Is(err, ErrInvalidMove) {
+			t.Errorf("Move %v: expected ErrInvalidMove, found %v", move, err)
+		}
+		if result := game.controller.Tick(move, game.preview[0]); result.Moved || result.Consumed {
+			t.Errorf("Move %v: expected the board controller to do nothing", move)
+		}
+	}
+
+	if after := game.Snap(); after.Ticks != before.Ticks || after.Position != before.Position {
+		t.Error("Expected the game to be left alone")
+	}
+
+	// Moves are still checked while waiting on the next tetromino
+	game = NewGame(0, 1, WithEntryDelay(time.Second))
+	game.Step([]Movement{MOVE_SLAM})
+	if err := game.Tick(-1); !errors.Is(err, ErrInvalidMove) || len(game.buffered) != 0 {
+		t.Errorf("Expected the move to be rejected rather than buffered, found %v", err)
+	}
+
+	if err := game.Tick(MOVE_LEFT); err != nil {
+		t.Errorf("Expected a valid move to be fine, found %v", err)
+	}
 }
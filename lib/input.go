@@ -0,0 +1,188 @@
+package lib
+
+import (
+	"time"
+)
+
+const DEFAULT_DAS = 167 * time.Millisecond
+const DEFAULT_ARR = 33 * time.Millisecond
+const DEFAULT_SOFT_DROP_FACTOR = 20
+
+// Settings for how held buttons repeat
+type InputConfig struct {
+	// Delayed auto shift. How long left or right has to be held before
+	// the tetromino starts moving on it's own
+	DAS time.Duration
+	// Auto repeat rate. How long between moves once it's started
+	// moving on it's own. Zero moves it all the way over right away
+	ARR time.Duration
+	// How many times faster than gravity a held soft drop falls
+	SoftDropFactor int
+}
+
+func DefaultInputConfig() InputConfig {
+	return InputConfig{
+		DAS:            DEFAULT_DAS,
+		ARR:            DEFAULT_ARR,
+		SoftDropFactor: DEFAULT_SOFT_DROP_FACTOR,
+	}
+}
+
+// Builds an InputConfig out of frame counts instead of durations, for
+// frames of the given length
+func InputConfigFrames(das, arr, softDropFactor int, frame time.Duration) InputConfig {
+	return InputConfig{
+		DAS:            time.Duration(das) * frame,
+		ARR:            time.Duration(arr) * frame,
+		SoftDropFactor: softDropFactor,
+	}
+}
+
+// Turns buttons being pressed and released into movements. Buttons are
+// named by the movement they make. Left, right and soft drop repeat
+// for as long as they're held, everything else only moves once per
+// press. Nothing here waits on time, whoever is using it says how much
+// time has passed, so it works the same with real time and frames.
+type InputHandler struct {
+	config InputConfig
+	held   map[Movement]bool
+	// The direction that's repeating, which is the one pressed last,
+	// and how long it's been held
+	shift     Movement
+	shifting  bool
+	shiftTime time.Duration
+	// With an instant repeat, whether the tetromino in play has been
+	// shifted to the wall already
+	pinned bool
+	// How long soft drop has been held
+	dropTime time.Duration
+	gravity  time.Duration
+}
+
+func NewInputHandler(config InputConfig) *InputHandler {
+	if config.SoftDropFactor < 1 {
+		config.SoftDropFactor = 1
+	}
+
+	return &InputHandler{
+		config:  config,
+		held:    make(map[Movement]bool),
+		gravity: DEFAULT_DURATION,
+	}
+}
+
+// Sets how long it takes the tetromino to fall a row. Soft drops are
+// that many times faster than this
+func (h *InputHandler) SetGravity(gravity time.Duration) {
+	h.gravity = gravity
+}
+
+// Presses a button, returning the movements that happen right away.
+// Pressing a button that's already held does nothing, so repeats
+// from the keyboard can be passed straight through.
+func (h *InputHandler) Press(button Movement) []Movement {
+	if h.held[button] {
+		return nil
+	}
+	h.held[button] = true
+
+	switch button {
+	case MOVE_LEFT, MOVE_RIGHT:
+		// The last direction pressed always wins
+		h.shift = button
+		h.shifting = true
+		h.shiftTime = 0
+		h.pinned = false
+	case MOVE_DOWN:
+		h.dropTime = 0
+	}
+
+	return []Movement{button}
+}
+
+// Releases a button. If the other direction is still held, it takes
+// over, and has to wait out the delay again before it repeats.
+func (h *InputHandler) Release(button Movement) {
+	if !h.held[button] {
+		return
+	}
+	delete(h.held, button)
+
+	if h.shifting && button == h.shift {
+		other := MOVE_LEFT
+		if button == MOVE_LEFT {
+			other = MOVE_RIGHT
+		}
+
+		h.shift = other
+		h.shifting = h.held[other]
+		h.shiftTime = 0
+		h.pinned = false
+	}
+}
+
+// Tells the handler a new tetromino has come into play. With an
+// instant repeat, a direction that's still held shifts it to the wall
+// as well, the last one only went as far as the wall once.
+func (h *InputHandler) NewPiece() {
+	h.pinned = false
+}
+
+// Returns true if a button is being held down
+func (h *InputHandler) Held(button Movement) bool {
+	return h.held[button]
+}
+
+// Moves time forward, returning the movements from held buttons that
+// repeated along the way
+func (h *InputHandler) Advance(elapsed time.Duration) []Movement {
+	var moves []Movement
+
+	if h.shifting {
+		before := h.shiftTime
+		h.shiftTime += elapsed
+
+		if h.config.ARR <= 0 {
+			// It only has to go to the wall once, moving it there again
+			// would still count as a move. See NewPiece
+			if h.shiftTime >= h.config.DAS && !h.pinned {
+				shift := MOVE_SHIFT_LEFT
+				if h.shift == MOVE_RIGHT {
+					shift = MOVE_SHIFT_RIGHT
+				}
+				moves = append(moves, shift)
+				h.pinned = true
+			}
+		} else {
+			for n := h.repeats(h.shiftTime) - h.repeats(before); n > 0; n-- {
+				moves = append(moves, h.shift)
+			}
+		}
+	}
+
+	if h.held[MOVE_DOWN] {
+		interval := h.gravity / time.Duration(h.config.SoftDropFactor)
+		if interval <= 0 {
+			interval = 1
+		}
+
+		// The first drop happened when it was pressed
+		before := h.dropTime
+		h.dropTime += elapsed
+		for n := h.dropTime/interval - before/interval; n > 0; n-- {
+			moves = append(moves, MOVE_DOWN)
+		}
+	}
+
+	return moves
+}
+
+// Returns the number of times a direction held for some amount of
+// time has repeated. The first repeat comes once the delay is up
+func (h *InputHandler) repeats(held time.Duration) int {
+	if held < h.config.DAS {
+		return 0
+	}
+
+	return int((held-h.config.DAS)/h.config.ARR) + 1
+}
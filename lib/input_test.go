@@ -0,0 +1,209 @@
+package lib
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Repeats a movement some number of times
+func repeated(move Movement, n int) []Movement {
+	var moves []Movement
+	for i := 0; i < n; i++ {
+		moves = append(moves, move)
+	}
+	return moves
+}
+
+func TestInputHandlerDAS(t *testing.T) {
+	const DAS = 100 * time.Millisecond
+	const ARR = 20 * time.Millisecond
+
+	h := NewInputHandler(InputConfig{DAS: DAS, ARR: ARR, SoftDropFactor: 1})
+
+	if moves := h.Press(MOVE_LEFT); !reflect.DeepEqual(moves, []Movement{MOVE_LEFT}) {
+		t.Errorf("Expected a single move on press, found %v", moves)
+	}
+
+	// Repeats from the keyboard don't count as new presses
+	if moves := h.Press(MOVE_LEFT); moves != nil {
+		t.Errorf("Expected nothing from pressing a held button, found %v", moves)
+	}
+
+	steps := []struct {
+		elapsed time.Duration
+		moves   int
+	}{
+		{DAS - time.Nanosecond, 0},
+		{time.Nanosecond, 1},
+		{ARR - time.Nanosecond, 0},
+		{time.Nanosecond, 1},
+		{ARR * 3, 3},
+	}
+
+	for i, step := range steps {
+		if moves := h.Advance(step.elapsed); !reflect.DeepEqual(moves, repeated(MOVE_LEFT, step.moves)) {
+			t.Errorf("Step %v: expected %v moves, found %v", i, step.moves, moves)
+		}
+	}
+
+	h.Release(MOVE_LEFT)
+	if moves := h.Advance(time.Second); moves != nil {
+		t.Errorf("Expected nothing after releasing, found %v", moves)
+	}
+}
+
+func TestInputHandlerFrames(t *testing.T) {
+	h := NewInputHandler(InputConfigFrames(10, 2, 1, DEFAULT_FRAME))
+	h.Press(MOVE_RIGHT)
+
+	var moved []int
+	for frame := 1; frame <= 15; frame++ {
+		if moves := h.Advance(DEFAULT_FRAME); len(moves) > 0 {
+			moved = append(moved, frame)
+		}
+	}
+
+	if expected := []int{10, 12, 14}; !reflect.DeepEqual(moved, expected) {
+		t.Errorf("Expected repeats on frames %v, found %v", expected, moved)
+	}
+}
+
+func TestInputHandlerInstantARR(t *testing.T) {
+	h := NewInputHandler(InputConfig{DAS: 50 * time.Millisecond, ARR: 0, SoftDropFactor: 1})
+	h.Press(MOVE_RIGHT)
+
+	if moves := h.Advance(49 * time.Millisecond); moves != nil {
+		t.Errorf("Expected nothing before the delay, found %v", moves)
+	}
+
+	// Once it's charged, it shifts to the wall just the once
+	if moves := h.Advance(time.Millisecond); !reflect.DeepEqual(moves, []Movement{MOVE_SHIFT_RIGHT}) {
+		t.Errorf("Expected a shift to the wall, found %v", moves)
+	}
+	if moves := h.Advance(time.Second); moves != nil {
+		t.Errorf("Expected nothing once it's at the wall, found %v", moves)
+	}
+
+	// The next tetromino goes to the wall too, if it's still held
+	h.NewPiece()
+	if moves := h.Advance(time.Millisecond); !reflect.DeepEqual(moves, []Movement{MOVE_SHIFT_RIGHT}) {
+		t.Errorf("Expected the new tetromino to shift to the wall, found %v", moves)
+	}
+
+	// So does the other direction, once it's charged
+	h.Press(MOVE_LEFT)
+	h.Advance(49 * time.Millisecond)
+	if moves := h.Advance(time.Millisecond); !reflect.DeepEqual(moves, []Movement{MOVE_SHIFT_LEFT}) {
+		t.Errorf("Expected a shift to the other wall, found %v", moves)
+	}
+}
+
+func TestInputHandlerDirectionPriority(t *testing.T) {
+	const DAS = 100 * time.Millisecond
+	h := NewInputHandler(InputConfig{DAS: DAS, ARR: DAS, SoftDropFactor: 1})
+
+	h.Press(MOVE_LEFT)
+	h.Advance(DAS / 2)
+
+	// The newest direction takes over
+	if moves := h.Press(MOVE_RIGHT); !reflect.DeepEqual(moves, []Movement{MOVE_RIGHT}) {
+		t.Errorf("Expected to move right, found %v", moves)
+	}
+	if moves := h.Advance(DAS); !reflect.DeepEqual(moves, []Movement{MOVE_RIGHT}) {
+		t.Errorf("Expected right to repeat, found %v", moves)
+	}
+
+	// Letting go hands it back to left, which waits out the delay
+	h.Release(MOVE_RIGHT)
+	if moves := h.Advance(DAS - time.Nanosecond); moves != nil {
+		t.Errorf("Expected left to wait out the delay again, found %v", moves)
+	}
+	if moves := h.Advance(time.Nanosecond); !reflect.DeepEqual(moves, []Movement{MOVE_LEFT}) {
+		t.Errorf("Expected left to repeat, found %v", moves)
+	}
+
+	// Letting go of the older direction changes nothing
+	h.Press(MOVE_RIGHT)
+	h.Release(MOVE_LEFT)
+	if moves := h.Advance(DAS); !reflect.DeepEqual(moves, []Movement{MOVE_RIGHT}) {
+		t.Errorf("Expected right to keep repeating, found %v", moves)
+	}
+}
+
+func TestInputHandlerSoftDrop(t *testing.T) {
+	h := NewInputHandler(InputConfig{DAS: time.Second, ARR: time.Second, SoftDropFactor: 20})
+	h.SetGravity(time.Second)
+
+	h.Press(MOVE_DOWN)
+
+	// 20 times faster than gravity is every 50ms
+	if moves := h.Advance(49 * time.Millisecond); moves != nil {
+		t.Errorf("Expected nothing before 50ms, found %v", moves)
+	}
+	if moves := h.Advance(51 * time.Millisecond); !reflect.DeepEqual(moves, repeated(MOVE_DOWN, 2)) {
+		t.Errorf("Expected two drops after 100ms, found %v", moves)
+	}
+
+	h.Release(MOVE_DOWN)
+	if moves := h.Advance(time.Second); moves != nil {
+		t.Errorf("Expected nothing after releasing, found %v", moves)
+	}
+}
+
+func TestInputHandlerNoRepeat(t *testing.T) {
+	h := NewInputHandler(DefaultInputConfig())
+
+	for _, button := range []Movement{MOVE_ROTATE_LEFT, MOVE_ROTATE_RIGHT, MOVE_SLAM, MOVE_HOLD} {
+		if moves := h.Press(button); !reflect.DeepEqual(moves, []Movement{button}) {
+			t.Errorf("Expected %v once on press, found %v", button, moves)
+		}
+
+		if moves := h.Advance(time.Second); moves != nil {
+			t.Errorf("Expected %v not to repeat, found %v", button, moves)
+		}
+
+		if !h.Held(button) {
+			t.Errorf("Expected %v to be held", button)
+		}
+		h.Release(button)
+	}
+}
+
+func TestInputHandlerStep(t *testing.T) {
+	// Holding right with an instant repeat takes the tetromino all the
+	// way to the wall once the delay is up
+	game := NewGame(0, 1)
+	h := NewInputHandler(InputConfigFrames(10, 0, 1, DEFAULT_FRAME))
+
+	game.Step(h.Press(MOVE_RIGHT))
+	for i := 0; i < 10; i++ {
+		game.Step(h.Advance(DEFAULT_FRAME))
+	}
+
+	if game.controller.tet.CanMove(RIGHT, game.controller.board) {
+		t.Errorf("Tetromino isn't against the wall, found it at %v", game.controller.tet.Position)
+	}
+
+	// Holding it there doesn't keep ticking the game over
+	ticks := game.ticks
+	for i := 0; i < 10; i++ {
+		game.Step(h.Advance(DEFAULT_FRAME))
+	}
+	if game.ticks != ticks {
+		t.Errorf("Expected no more ticks while it's at the wall, found %v", game.ticks-ticks)
+	}
+
+	// The snapshots show when the next tetromino comes in, which goes
+	// to the wall as well
+	piece := game.Snap().Piece
+	game.Step([]Movement{MOVE_SLAM})
+	if game.Snap().Piece == piece {
+		t.Fatal("Expected the snapshot to show a new tetromino")
+	}
+	h.NewPiece()
+	game.Step(h.Advance(DEFAULT_FRAME))
+	if game.controller.tet.CanMove(RIGHT, game.controller.board) {
+		t.Errorf("Next tetromino isn't against the wall, found it at %v", game.controller.tet.Position)
+	}
+}
@@ -0,0 +1,97 @@
+package lib
+
+import (
+	"time"
+)
+
+// How long a single frame lasts on an NTSC NES, which runs at about
+// 60.0988 frames a second
+const NES_FRAME = time.Second * 10000 / 600988
+
+// The number of frames it takes a tetromino to fall one row on the NES,
+// indexed by level. Every level past the end of the table is as fast
+// as the last entry.
+var nesFramesPerRow = []int{
+	48, 43, 38, 33, 28, 23, 18, 13, 8, 6, // 0-9
+	5, 5, 5, 4, 4, 4, 3, 3, 3, // 10-18
+	2, 2, 2, 2, 2, 2, 2, 2, 2, 2, // 19-28
+	1, // 29 and up
+}
+
+// Returns the time it takes a tetromino to fall one row at a given
+// level on the NES
+func nesGravity(level int) time.Duration {
+	if level >= len(nesFramesPerRow) {
+		level = len(nesFramesPerRow) - 1
+	}
+
+	return time.Duration(nesFramesPerRow[level]) * NES_FRAME
+}
+
+// Returns the level on the NES after clearing some number of lines.
+// Starting on a higher level takes more lines before the first level
+// up, after which it's every 10 lines. There's no maximum level.
+func nesLevel(startingLevel, lines int) int {
+	// The first level up is the smaller of the starting level plus one
+	// times 10, and the larger of 100 and the starting level minus 5
+	// times 10
+	first := startingLevel*10 - 50
+	if first < 100 {
+		first = 100
+	}
+	if sooner := startingLevel*10 + 10; sooner < first {
+		first = sooner
+	}
+
+	if lines < first {
+		return startingLevel
+	}
+
+	return startingLevel + 1 + (lines-first)/10
+}
+
+// The NES waits between 10 and 18 frames to bring in the next
+// tetromino, depending on how high the last one locked. The shortest
+// wait is used for every lock here. Clearing lines takes about another
+// 18 frames while the rows are animated away.
+const NES_ENTRY_DELAY = 10 * NES_FRAME
+const NES_LINE_CLEAR_DELAY = 18 * NES_FRAME
+
+// Points for clearing lines on the NES, before they're multiplied by
+// one more than the level
+var nesLineScores = [...]int{0, 40, 100, 300, 1200}
+
+// Scoring as done by the NES. Only clearing lines and soft dropping
+// are worth anything, there's nothing extra for spins or combos.
+type NESScorer struct{}
+
+func (NESScorer) Score(event ScoreEvent) int {
+	lines := event.Lines
+	if lines >= len(nesLineScores) {
+		lines = len(nesLineScores) - 1
+	}
+
+	return event.SoftDrop + nesLineScores[lines]*(event.Level+1)
+}
+
+// Plays the game the way the NES version does. It uses the NES
+// rotation, scoring, randomizer, gravity, level progression and
+// delays between tetrominos, and turns off the lock delay. Tetrominos
+// come in where they always have, and the game only ends when there's
+// no room for the next one. Stepped games run at the NES frame rate.
+// Options after this one can still change any of them.
+func WithNES() GameOption {
+	return func(game *Game) {
+		game.rotation = NESRotation{}
+		game.rules = Ruleset{SpawnY: 1}
+		game.scorer = NESScorer{}
+		game.newRandomizer = NewNESRandomizer
+		game.gravity = nesGravity
+		game.leveling = nesLevel
+		game.lockDelay = 0
+		game.maxResets = 0
+		game.entryDelay = NES_ENTRY_DELAY
+		game.lineClearDelay = NES_LINE_CLEAR_DELAY
+		game.frame = NES_FRAME
+	}
+}
@@ -0,0 +1,71 @@
+package lib
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNESGravity(t *testing.T) {
+	expected := map[int]int{0: 48, 9: 6, 10: 5, 18: 3, 19: 2, 28: 2, 29: 1, 100: 1}
+
+	for level, frames := range expected {
+		if d := nesGravity(level); d != time.Duration(frames)*NES_FRAME {
+			t.Errorf("Level %v expected %v frames per row, found %v", level, frames, d)
+		}
+	}
+}
+
+func TestNESLevel(t *testing.T) {
+	// The number of lines it takes to get past the starting level
+	firstLevelUp := map[int]int{0: 10, 5: 60, 9: 100, 12: 100, 15: 100, 16: 110, 18: 130, 19: 140}
+
+	for start, lines := range firstLevelUp {
+		if lvl := nesLevel(start, lines-1); lvl != start {
+			t.Errorf("Starting on %v, expected to still be there after %v lines, found %v", start, lines-1, lvl)
+		}
+		if lvl := nesLevel(start, lines); lvl != start+1 {
+			t.Errorf("Starting on %v, expected a level up after %v lines, found %v", start, lines, lvl)
+		}
+		if lvl := nesLevel(start, lines+10); lvl != start+2 {
+			t.Errorf("Starting on %v, expected another level up 10 lines later, found %v", start, lvl)
+		}
+	}
+}
+
+func TestNESScorer(t *testing.T) {
+	scorer := NESScorer{}
+
+	for lines, expected := range []int{0, 80, 200, 600, 2400} {
+		if score := scorer.Score(ScoreEvent{Locked: true, Lines: lines, Level: 1}); score != expected {
+			t.Errorf("Expected %v for %v lines at level 1, found %v", expected, lines, score)
+		}
+	}
+
+	// Nothing for hard drops or spins
+	if score := scorer.Score(ScoreEvent{Locked: true, HardDrop: 10, TSpin: TSPIN, Level: 1}); score != 0 {
+		t.Errorf("Expected no points for hard drops or spins, found %v", score)
+	}
+}
+
+func TestGameNES(t *testing.T) {
+	game := NewGame(0, 0, WithNES())
+
+	if _, ok := game.rotation.(NESRotation); !ok {
+		t.Errorf("Expected NES rotation, found %T", game.rotation)
+	}
+
+	if game.lockDelay != 0 {
+		t.Errorf("Expected no lock delay, found %v", game.lockDelay)
+	}
+
+	game.ClearLines(9)
+	if game.Level() != 0 {
+		t.Errorf("Expected to still be on level 0, found %v", game.Level())
+	}
+
+	// Past MAX_LEVEL, which only applies to the default progression
+	game.ClearLines(300)
+	if game.Level() != 30 {
+		t.Errorf("Expected level 30, found %v", game.Level())
+	}
+}
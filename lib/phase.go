@@ -0,0 +1,150 @@
+package lib
+
+import (
+	"time"
+)
+
+// What a game is doing at a given moment. Most of the time there's a
+// tetromino in play, but there can be delays after it locks, while the
+// full rows are cleared and before the next tetromino comes in.
+type Phase int
+
+const (
+	// A tetromino is in play and can be moved
+	PHASE_ACTIVE Phase = iota
+	// The full rows are still on the board, waiting to be cleared
+	PHASE_LINE_CLEAR
+	// Waiting for the next tetromino to come in, also known as ARE
+	PHASE_ENTRY
+)
+
+func (phase Phase) String() string {
+	switch phase {
+	case PHASE_ACTIVE:
+		return "active"
+	case PHASE_LINE_CLEAR:
+		return "line clear"
+	case PHASE_ENTRY:
+		return "entry"
+	default:
+		return "unknown"
+	}
+}
+
+// Sets how long full rows stay on the board before they're cleared,
+// so they can be shown going away. Games have no line clear delay by
+// default.
+func WithLineClearDelay(delay time.Duration) GameOption {
+	return func(game *Game) {
+		game.lineClearDelay = delay
+	}
+}
+
+// Sets how long it takes the next tetromino to come in after one is
+// locked, which comes after the line clear delay if there is one.
+// Games have no entry delay by default.
+func WithEntryDelay(delay time.Duration) GameOption {
+	return func(game *Game) {
+		game.entryDelay = delay
+	}
+}
+
+// Lets the game see which buttons are held down, for the initial
+// rotation and hold systems (IRS and IHS). When a tetromino comes in
+// after a delay, it goes straight into hold if the hold button is
+// down, and comes in turned if a rotate button is. Rotations and holds
+// made during the delay aren't buffered then, since keeping the button
+// held is how they're made.
+func WithHeldButtons(held func(button Movement) bool) GameOption {
+	return func(game *Game) {
+		game.held = held
+	}
+}
+
+// Starts whichever delay comes after a lock, given the rows the
+// tetromino filled up. Brings in the next tetromino right away if
+// there's no delay to wait on.
+func (game *Game) startDelay(rows []int) {
+	if game.controller.isGameover {
+		return
+	}
+
+	if len(rows) > 0 && game.clearTimer != nil {
+		game.phase = PHASE_LINE_CLEAR
+		game.clearing = rows
+		game.clearTimer.Reset()
+		return
+	}
+
+	game.controller.board.Tetris()
+
+	if game.entryTimer != nil {
+		game.phase = PHASE_ENTRY
+		game.entryTimer.Reset()
+		return
+	}
+
+	game.spawnNext()
+}
+
+// Moves on once the current delay is over. The full rows are cleared
+// after the line clear delay, and the next tetromino is brought in
+// after the entry delay.
+func (game *Game) finishDelay() {
+	switch game.phase {
+	case PHASE_LINE_CLEAR:
+		game.clearTimer.Stop()
+		game.clearing = nil
+		game.startDelay(nil)
+	case PHASE_ENTRY:
+		game.entryTimer.Stop()
+		game.spawnNext()
+	}
+}
+
+// Brings in the next tetromino, and then makes the moves that were
+// held onto while waiting for it. If there was a wait, any held
+// rotation or hold is applied as it comes in.
+func (game *Game) spawnNext() {
+	initial := game.phase != PHASE_ACTIVE && game.held != nil
+	game.phase = PHASE_ACTIVE
+
+	next := game.preview[0]
+	game.NextTet()
+
+	if initial && game.held(MOVE_HOLD) {
+		// The tetromino never comes in, it goes straight into hold
+		if game.heldTet == nil {
+			game.heldTet, next = next, game.preview[0]
+			game.NextTet()
+		} else {
+			game.heldTet, next = next, game.heldTet
+		}
+		game.holdUsed = true
+	}
+
+	switch {
+	case initial && game.held(MOVE_ROTATE_LEFT):
+		game.controller.spawnRotated(next, true)
+	case initial && game.held(MOVE_ROTATE_RIGHT):
+		game.controller.spawnRotated(next, false)
+	default:
+		game.controller.spawn(next)
+	}
+	game.updateLockDelay(false, true)
+
+	buffered := game.buffered
+	game.buffered = nil
+	for _, move := range buffered {
+		if game.controller.isGameover {
+			break
+		}
+
+		// These were taken care of by the held buttons
+		if game.held != nil && (move == MOVE_ROTATE_LEFT || move == MOVE_ROTATE_RIGHT || move == MOVE_HOLD) {
+			continue
+		}
+
+		game.Tick(move)
+	}
+}
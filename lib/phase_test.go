@@ -0,0 +1,274 @@
+package lib
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Fills the bottom row of the board everywhere except where the
+// current tetromino will land, so slamming it clears the row
+func fillUnderGhost(game *Game) {
+	gaps := make(map[int]bool)
+	for _, p := range game.controller.Ghost() {
+		if p.y == 0 {
+			gaps[p.x] = true
+		}
+	}
+
+	for x := 0; x < BOARD_WIDTH; x++ {
+		if !gaps[x] {
+			game.controller.board.SetTile(C1, x, 0)
+		}
+	}
+}
+
+func TestStepLineClearDelay(t *testing.T) {
+	const CLEAR_FRAMES = 5
+	const ENTRY_FRAMES = 3
+
+	game := NewGame(0, 1,
+		WithLineClearDelay(CLEAR_FRAMES*DEFAULT_FRAME),
+		WithEntryDelay(ENTRY_FRAMES*DEFAULT_FRAME))
+	game.Step(nil)
+
+	fillUnderGhost(game)
+	next := game.preview[0]
+	game.Step([]Movement{MOVE_SLAM})
+
+	// The full row stays on the board for the renderer
+	snap := game.Snap()
+	if snap.Phase != PHASE_LINE_CLEAR {
+		t.Fatalf("Expected to be clearing lines, found %v", snap.Phase)
+	}
+	if !reflect.DeepEqual(snap.Clearing, []int{0}) {
+		t.Errorf("Expected the bottom row to be clearing, found %v", snap.Clearing)
+	}
+	if len(snap.Board.FullLines()) != 1 {
+		t.Errorf("Expected the full row to still be on the board")
+	}
+	if view := snap.View(); snap.Ghost != nil || !view.Equal(&snap.Board) {
+		t.Errorf("Expected nothing to be in play while clearing lines")
+	}
+	if game.lines != 1 {
+		t.Errorf("Expected the line to be counted when it locked, found %v", game.lines)
+	}
+
+	stepFrames(game, CLEAR_FRAMES-1)
+	if game.phase != PHASE_LINE_CLEAR {
+		t.Fatal("Lines cleared before the delay was up")
+	}
+
+	game.Step(nil)
+	if game.phase != PHASE_ENTRY || len(game.controller.board.FullLines()) != 0 {
+		t.Fatalf("Expected the row to be cleared and to be waiting, found %v", game.phase)
+	}
+
+	stepFrames(game, ENTRY_FRAMES-1)
+	if game.phase != PHASE_ENTRY {
+		t.Fatal("Tetromino came in before the delay was up")
+	}
+
+	game.Step(nil)
+	if game.phase != PHASE_ACTIVE || game.controller.tet.Tetromino != next {
+		t.Errorf("Expected the next tetromino to be in play, found %v", game.phase)
+	}
+}
+
+func TestStepEntryDelayNoLines(t *testing.T) {
+	// Without any full rows, there's no line clear delay to wait out
+	game := NewGame(0, 1,
+		WithLineClearDelay(time.Second),
+		WithEntryDelay(2*DEFAULT_FRAME))
+
+	game.Step([]Movement{MOVE_SLAM})
+	if game.phase != PHASE_ENTRY {
+		t.Fatalf("Expected to be waiting on the next tetromino, found %v", game.phase)
+	}
+
+	stepFrames(game, 2)
+	if game.phase != PHASE_ACTIVE {
+		t.Errorf("Expected the next tetromino to be in play, found %v", game.phase)
+	}
+}
+
+func TestStepBuffersInput(t *testing.T) {
+	game := NewGame(0, 1, WithEntryDelay(10*DEFAULT_FRAME))
+
+	game.Step([]Movement{MOVE_SLAM})
+	ticks := game.ticks
+	spawn := game.rules.SpawnPosition(game.preview[0], game.controller.board)
+
+	// Moves made while waiting are made as soon as the tetromino is in
+	game.Step([]Movement{MOVE_LEFT, MOVE_LEFT})
+	if game.ticks != ticks {
+		t.Fatal("Moves were made with nothing in play")
+	}
+
+	stepFrames(game, 9)
+	if x := game.controller.tet.x; x != spawn.x-2 {
+		t.Errorf("Expected the buffered moves to be made, found the tetromino at x=%v", x)
+	}
+}
+
+func TestStepNoDelays(t *testing.T) {
+	// Without delays the next tetromino comes in on the same frame
+	game := NewGame(0, 1)
+	game.Step(nil)
+
+	fillUnderGhost(game)
+	next := game.preview[0]
+	game.Step([]Movement{MOVE_SLAM})
+
+	if game.phase != PHASE_ACTIVE || game.controller.tet.Tetromino != next {
+		t.Errorf("Expected the next tetromino to be in play, found %v", game.phase)
+	}
+	if len(game.controller.board.FullLines()) != 0 {
+		t.Errorf("Expected the row to be cleared right away")
+	}
+}
+
+func TestGamePlayEntryDelay(t *testing.T) {
+	const DELAY = 100 * time.Millisecond
+
+	clock := NewFakeClock(time.Time{})
+	game := NewGame(0, 1, WithClock(clock), WithEntryDelay(DELAY))
+	moves, snaps, stop := playFake(t, game)
+	defer stop()
+
+	spawn := game.rules.SpawnPosition(game.preview[0], game.controller.board)
+	moves <- MOVE_SLAM
+	if snap := nextSnap(t, snaps); snap.Phase != PHASE_ENTRY {
+		t.Fatalf("Expected to be waiting on the next tetromino, found %v", snap.Phase)
+	}
+
+	// Gravity doesn't do anything while waiting
+	clock.Advance(DELAY - time.Nanosecond)
+	moves <- MOVE_RIGHT
+	if snap := nextSnap(t, snaps); snap.Phase != PHASE_ENTRY {
+		t.Fatalf("Tetromino came in before the delay was up")
+	}
+
+	clock.Advance(time.Nanosecond)
+	snap := nextSnap(t, snaps)
+	if snap.Phase != PHASE_ACTIVE {
+		t.Fatalf("Expected the next tetromino to be in play, found %v", snap.Phase)
+	}
+	if x, _ := snap.Position.GetPos(); x != spawn.x+1 {
+		t.Errorf("Expected the buffered move to be made, found the tetromino at x=%v", x)
+	}
+}
+
+// Sets up a game with an entry delay that watches the buttons on an
+// input handler, and has locked it's first tetromino. The tetromino
+// passed comes in next.
+func waitingGame(t *testing.T, next *Tetromino) (*Game, *InputHandler) {
+	t.Helper()
+
+	h := NewInputHandler(DefaultInputConfig())
+	game := NewGame(0, 1,
+		WithEntryDelay(2*DEFAULT_FRAME),
+		WithHeldButtons(h.Held),
+		WithPreview(2))
+
+	game.rotation.Spawn(next)
+	game.preview[0] = next
+
+	game.Step([]Movement{MOVE_SLAM})
+	if game.phase != PHASE_ENTRY {
+		t.Fatalf("Expected to be waiting on the next tetromino, found %v", game.phase)
+	}
+
+	return game, h
+}
+
+func TestStepInitialRotation(t *testing.T) {
+	for _, tc := range []struct {
+		button Movement
+		turns  int
+	}{
+		{MOVE_ROTATE_LEFT, 1},
+		{MOVE_ROTATE_RIGHT, 3},
+	} {
+		next := NewTet(TET_T)
+		game, h := waitingGame(t, next)
+		expected := (next.rotationIdx + tc.turns) % 4
+
+		// Only rotates once, even though the press was buffered
+		game.Step(h.Press(tc.button))
+		game.Step(h.Advance(DEFAULT_FRAME))
+
+		if game.controller.tet.Tetromino != next || next.rotationIdx != expected {
+			t.Errorf("Expected the tetromino to come in turned to %v, found %v", expected, next.rotationIdx)
+		}
+	}
+}
+
+func TestStepInitialRotationReleased(t *testing.T) {
+	next := NewTet(TET_T)
+	game, h := waitingGame(t, next)
+	expected := next.rotationIdx
+
+	// Letting go before it comes in means it isn't turned at all
+	game.Step(h.Press(MOVE_ROTATE_LEFT))
+	h.Release(MOVE_ROTATE_LEFT)
+	game.Step(nil)
+
+	if game.phase != PHASE_ACTIVE || next.rotationIdx != expected {
+		t.Errorf("Expected the tetromino not to be turned, found %v", next.rotationIdx)
+	}
+}
+
+func TestStepInitialRotationSavesTopOut(t *testing.T) {
+	for _, hold := range []bool{false, true} {
+		game, h := waitingGame(t, NewTet(TET_LINE))
+		board := game.controller.board
+
+		// Block a tile the line covers lying flat where it comes in, but
+		// not once it's turned
+		turned := NewTet(TET_LINE)
+		game.rotation.Spawn(turned)
+		tet := ActiveTetromino{turned, game.rules.SpawnPosition(turned, board)}
+		flat := tet.ListPositions()
+		upright, _ := game.rotation.Rotate(tet, true, board)
+
+		covered := make(map[Position]bool)
+		for _, p := range upright.ListPositions() {
+			covered[p] = true
+		}
+		for _, p := range flat {
+			if !covered[p] {
+				board.SetTile(C1, p.x, p.y)
+				break
+			}
+		}
+
+		if hold {
+			h.Press(MOVE_ROTATE_LEFT)
+		}
+		stepFrames(game, 2)
+
+		if game.IsGameover() == hold {
+			t.Errorf("Holding rotate: %v, expected gameover to be %v", hold, !hold)
+		}
+	}
+}
+
+func TestStepInitialHold(t *testing.T) {
+	next := NewTet(TET_T)
+	game, h := waitingGame(t, next)
+	after := game.preview[1]
+
+	h.Press(MOVE_HOLD)
+	stepFrames(game, 2)
+
+	if game.heldTet != next {
+		t.Errorf("Expected the tetromino to go straight into hold")
+	}
+	if game.controller.tet.Tetromino != after {
+		t.Errorf("Expected the tetromino after it to come in")
+	}
+	if !game.holdUsed {
+		t.Errorf("Expected the hold to be used up")
+	}
+}
@@ -0,0 +1,259 @@
+package lib
+
+import (
+	"math/rand"
+)
+
+// A Randomizer decides which shape comes next. Every randomizer is
+// seeded, so the same seed always deals the same shapes. Nothing runs
+// in the background, shapes are only picked when they're asked for.
+type Randomizer interface {
+	Next() Shape
+	// Returns an independent copy, which deals the same shapes from
+	// here on as the original does
+	Clone() Randomizer
+}
+
+// Randomizers by the names they're commonly known by, for picking one
+// from a flag
+var Randomizers = map[string]func(seed int64) Randomizer{
+	"7bag":  NewSevenBagRandomizer,
+	"14bag": NewFourteenBagRandomizer,
+	"pure":  NewPureRandomizer,
+	"tgm":   NewTGMRandomizer,
+	"tgm2":  NewTGM2Randomizer,
+	"nes":   NewNESRandomizer,
+}
+
+// Deals shapes out of a shuffled bag, which holds the same number of
+// each shape. Once the bag is empty it's shuffled again, so the same
+// shape can never go too long without showing up.
+type BagRandomizer struct {
+	r   seededRand
+	bag []Shape
+	idx int
+}
+
+func newBagRandomizer(seed int64, copies int) *BagRandomizer {
+	return newBagRandomizerOf(seed, copies, shapes)
+}
+
+func newBagRandomizerOf(seed int64, copies int, contents []Shape) *BagRandomizer {
+	bag := &BagRandomizer{r: newSeededRand(seed)}
+	for i := 0; i < copies; i++ {
+		bag.bag = append(bag.bag, contents...)
+	}
+
+	bag.shuffle()
+	return bag
+}
+
+// A bag with one of each shape. This is what the guideline uses
+func NewSevenBagRandomizer(seed int64) Randomizer {
+	return newBagRandomizer(seed, 1)
+}
+
+// A bag with two of each shape, which lets the same shape come up
+// twice in a row, but also lets it go missing for longer
+func NewFourteenBagRandomizer(seed int64) Randomizer {
+	return newBagRandomizer(seed, 2)
+}
+
+// A bag holding one of each of the given shapes, for games played
+// with registered pieces. Shapes can be listed more than once to make
+// them come up more often.
+func NewBagRandomizerOf(seed int64, contents ...Shape) Randomizer {
+	if len(contents) == 0 {
+		panic("Bag must hold at least one shape")
+	}
+
+	return newBagRandomizerOf(seed, 1, contents)
+}
+
+// The bag is shuffled in place rather than being refilled in order,
+// which keeps the sequences the same as they've always been
+func (bag *BagRandomizer) shuffle() {
+	bag.r.Shuffle(len(bag.bag), func(i, j int) {
+		bag.bag[i], bag.bag[j] = bag.bag[j], bag.bag[i]
+	})
+}
+
+func (bag *BagRandomizer) Next() Shape {
+	if bag.idx == len(bag.bag) {
+		bag.shuffle()
+		bag.idx = 0
+	}
+
+	s := bag.bag[bag.idx]
+	bag.idx++
+	return s
+}
+
+func (bag *BagRandomizer) Clone() Randomizer {
+	clone := *bag
+	clone.r = bag.r.clone()
+	clone.bag = append([]Shape{}, bag.bag...)
+	return &clone
+}
+
+// Picks every shape independently, with the same odds for each
+type PureRandomizer struct {
+	r seededRand
+}
+
+func NewPureRandomizer(seed int64) Randomizer {
+	return &PureRandomizer{r: newSeededRand(seed)}
+}
+
+func (p *PureRandomizer) Next() Shape {
+	return shapes[p.r.Intn(len(shapes))]
+}
+
+func (p *PureRandomizer) Clone() Randomizer {
+	return &PureRandomizer{r: p.r.clone()}
+}
+
+// The randomizer used by the TGM series. It remembers the last four
+// shapes, and when it picks one of those it tries again, up to a fixed
+// number of rolls. The last roll is used no matter what. The first
+// shape is never an S, Z or square, since they'd force an overhang.
+type TGMRandomizer struct {
+	r       seededRand
+	history [4]Shape
+	rolls   int
+	started bool
+}
+
+// The first TGM, which starts with a history of all Z's and rolls up
+// to four times
+func NewTGMRandomizer(seed int64) Randomizer {
+	return &TGMRandomizer{
+		r:       newSeededRand(seed),
+		history: [4]Shape{TET_Z, TET_Z, TET_Z, TET_Z},
+		rolls:   4,
+	}
+}
+
+// TGM2, which starts with a history of Z, S, S, Z and rolls up to six
+// times
+func NewTGM2Randomizer(seed int64) Randomizer {
+	return &TGMRandomizer{
+		r:       newSeededRand(seed),
+		history: [4]Shape{TET_Z, TET_S, TET_S, TET_Z},
+		rolls:   6,
+	}
+}
+
+// The shapes the TGM randomizer is allowed to start with
+var tgmFirstShapes = []Shape{TET_L, TET_T, TET_J, TET_LINE}
+
+func (tgm *TGMRandomizer) Next() Shape {
+	var s Shape
+
+	if !tgm.started {
+		s = tgmFirstShapes[tgm.r.Intn(len(tgmFirstShapes))]
+		tgm.started = true
+	} else {
+		for i := 0; i < tgm.rolls; i++ {
+			s = shapes[tgm.r.Intn(len(shapes))]
+			if !tgm.inHistory(s) {
+				break
+			}
+		}
+	}
+
+	copy(tgm.history[1:], tgm.history[:len(tgm.history)-1])
+	tgm.history[0] = s
+	return s
+}
+
+func (tgm *TGMRandomizer) Clone() Randomizer {
+	clone := *tgm
+	clone.r = tgm.r.clone()
+	return &clone
+}
+
+func (tgm *TGMRandomizer) inHistory(s Shape) bool {
+	for _, h := range tgm.history {
+		if h == s {
+			return true
+		}
+	}
+	return false
+}
+
+// The randomizer used by the NES. A shape is picked out of 8 options,
+// where the last one isn't a shape at all. If that one comes up, or
+// the shape is the same as the last one, it's picked again out of the
+// 7 real shapes and whatever comes up is used.
+type NESRandomizer struct {
+	r    seededRand
+	prev int
+}
+
+func NewNESRandomizer(seed int64) Randomizer {
+	return &NESRandomizer{r: newSeededRand(seed), prev: -1}
+}
+
+func (nes *NESRandomizer) Next() Shape {
+	roll := nes.r.Intn(len(shapes) + 1)
+	if roll == len(shapes) || roll == nes.prev {
+		roll = nes.r.Intn(len(shapes))
+	}
+
+	nes.prev = roll
+	return shapes[roll]
+}
+
+func (nes *NESRandomizer) Clone() Randomizer {
+	return &NESRandomizer{r: nes.r.clone(), prev: nes.prev}
+}
+
+// A random number generator that can be cloned. The state of the
+// standard library's sources can't be copied, so instead the source
+// keeps track of it's seed and how many numbers it's handed out. A
+// clone is seeded the same way and then skips ahead, which gives the
+// exact same numbers as the standard source from then on.
+type seededRand struct {
+	*rand.Rand
+	src *seededSource
+}
+
+func newSeededRand(seed int64) seededRand {
+	src := &seededSource{src: rand.NewSource(seed).(rand.Source64), seed: seed}
+	return seededRand{Rand: rand.New(src), src: src}
+}
+
+// Cloning takes as long as it took to draw every number so far. That's
+// only a few numbers per shape, so it's cheap for any real game
+func (r seededRand) clone() seededRand {
+	clone := newSeededRand(r.src.seed)
+	for clone.src.draws < r.src.draws {
+		clone.src.Uint64()
+	}
+	return clone
+}
+
+// Wraps a standard source, counting every number drawn from it. Both
+// Int63 and Uint64 advance the standard source by a single step.
+type seededSource struct {
+	src   rand.Source64
+	seed  int64
+	draws uint64
+}
+
+func (s *seededSource) Int63() int64 {
+	s.draws++
+	return s.src.Int63()
+}
+
+func (s *seededSource) Uint64() uint64 {
+	s.draws++
+	return s.src.Uint64()
+}
+
+func (s *seededSource) Seed(seed int64) {
+	s.src.Seed(seed)
+	s.seed = seed
+	s.draws = 0
+}
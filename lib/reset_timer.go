@@ -1,62 +1,116 @@
 package lib
 
 import (
+	"sync"
 	"time"
 )
 
-// A timer that sends a signal after a specified duration, but has a
-// reset channel that will reset the timer. As long as the signal
-// get's applied, it will never fire
+// A timer that sends a signal after a specified duration, and keeps
+// sending one every duration after that. Resetting it starts the
+// duration over, so as long as it keeps getting reset, it will never
+// fire
 type ResetTimer struct {
-	reset         chan struct{}
-	internalTimer *time.Timer
-	out           chan struct{}
-	duration      time.Duration
+	mu       sync.Mutex
+	clock    Clock
+	timer    Timer
+	out      chan struct{}
+	duration time.Duration
+	// Bumped every time the timer is stopped or reset. A timer that
+	// goes off with an older generation was cancelled, and is ignored
+	gen int
 }
 
 func NewResetTimer(duration time.Duration) *ResetTimer {
-	timer := time.NewTimer(duration)
-	resetChan := make(chan struct{})
-	outChan := make(chan struct{})
+	return NewResetTimerWithClock(RealClock, duration)
+}
 
+// Creates a reset timer that waits on the given clock. It starts
+// running right away
+func NewResetTimerWithClock(clock Clock, duration time.Duration) *ResetTimer {
 	rTimer := &ResetTimer{
-		reset:         resetChan,
-		internalTimer: timer,
-		out:           outChan,
-		duration:      duration,
+		clock: clock,
+		// The output is buffered so the timer never waits on whoever
+		// is listening. If a signal hasn't been received yet, newer
+		// ones are dropped rather than piling up.
+		out:      make(chan struct{}, 1),
+		duration: duration,
 	}
 
-	// Manages logic internally for handling signals to the channels
-	go func() {
-		for {
-			select {
-			case <-resetChan:
-				timer.Stop()
-				timer.Reset(rTimer.duration)
-			case <-timer.C:
-				timer.Reset(rTimer.duration)
-				var outSignal struct{}
-				outChan <- outSignal
-			}
-		}
-	}()
+	rTimer.mu.Lock()
+	rTimer.schedule()
+	rTimer.mu.Unlock()
 
 	return rTimer
+}
 
+// Waits a full duration from now. Must be called with the lock held
+func (t *ResetTimer) schedule() {
+	gen := t.gen
+	t.timer = t.clock.AfterFunc(t.duration, func() {
+		t.fire(gen)
+	})
 }
 
-func (t *ResetTimer) Reset() {
-	var resetSig struct{}
+// Stops the pending wait, and throws away any signal that hasn't been
+// received yet, so nothing fires early after a reset or stop. Must be
+// called with the lock held
+func (t *ResetTimer) halt() {
+	t.gen++
+	if t.timer != nil {
+		t.timer.Stop()
+	}
 
-	// Forces method to be non blocking. Avoids deadlocks when we're
-	// selecting on the timer output and perform a reset.
 	select {
-	case t.reset <- resetSig:
+	case <-t.out:
 	default:
+	}
+}
+
+func (t *ResetTimer) fire(gen int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if gen != t.gen {
 		return
 	}
+
+	t.schedule()
+
+	var outSignal struct{}
+	select {
+	case t.out <- outSignal:
+	default:
+	}
+}
+
+// Restarts the timer, so the next signal is a full duration away
+func (t *ResetTimer) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.halt()
+	t.schedule()
 }
 
+// Stops the timer from sending any more signals until it's reset
 func (t *ResetTimer) Stop() {
-	t.internalTimer.Stop()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.halt()
+}
+
+// Changes how long the timer waits. It takes effect the next time the
+// timer goes off or is reset
+func (t *ResetTimer) SetDuration(duration time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.duration = duration
+}
+
+// Stops the timer for good. Nothing is left running in the background
+// afterwards, so it's safe to just drop the timer
+func (t *ResetTimer) Close() {
+	t.Stop()
 }
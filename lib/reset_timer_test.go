@@ -5,31 +5,42 @@ import (
 	"time"
 )
 
+// Returns true if the timer has sent a signal that hasn't been
+// received yet. The fake clock sends them while it's advancing, so
+// there's never a need to wait
+func fired(rTimer *ResetTimer) bool {
+	select {
+	case <-rTimer.out:
+		return true
+	default:
+		return false
+	}
+}
+
 func TestResetTimerNoReset(t *testing.T) {
 	const DURATION = time.Microsecond * 500
-	// Create a timer that resets
-
-	rTimer := NewResetTimer(DURATION)
-
-	t0 := time.Now()
-	// Receive 3 values
-	<-rTimer.out
-	<-rTimer.out
-	<-rTimer.out
-
-	t1 := time.Now()
-
-	// Calculate the duration. Note that this will fail at smaller
-	// times because there just isn't enough accuracy.
-	dur := t1.Sub(t0)
-
-	minDur := DURATION * 3
-	maxDur := DURATION * 4
+	// Create a timer that doesn't get reset
+
+	clock := NewFakeClock(time.Time{})
+	rTimer := NewResetTimerWithClock(clock, DURATION)
+
+	// Receive 3 values, each exactly one duration apart
+	for i := 0; i < 3; i++ {
+		clock.Advance(DURATION - time.Nanosecond)
+		if fired(rTimer) {
+			t.Fatalf("Timer %v fired early", i)
+		}
+
+		clock.Advance(time.Nanosecond)
+		if !fired(rTimer) {
+			t.Fatalf("Timer %v didn't fire after %v", i, DURATION)
+		}
+	}
 
 	rTimer.Stop()
-
-	if dur < minDur || dur > maxDur {
-		t.Errorf("Timing error: Expected duration between %v and %v , Found %v", minDur, maxDur, dur)
+	clock.Advance(DURATION * 10)
+	if fired(rTimer) {
+		t.Error("Timer fired after being stopped")
 	}
 }
 
@@ -37,38 +48,48 @@ func TestResetTimerNoReset(t *testing.T) {
 func TestResetTimerReset(t *testing.T) {
 	const DURATION = time.Microsecond * 500
 
-	rTimer := NewResetTimer(DURATION)
-
-	// Receive 3 values
+	clock := NewFakeClock(time.Time{})
+	rTimer := NewResetTimerWithClock(clock, DURATION)
 
-	// Create a ticker that sends values at a regular interval, that's
-	// less than our duration
-	ticker := time.NewTicker(DURATION / 2)
-
-	t0 := time.Now()
-
-	<-ticker.C
+	// Reset at regular intervals, that are less than our duration
+	clock.Advance(DURATION / 2)
 	rTimer.Reset()
 	// 250
-	<-ticker.C
+	clock.Advance(DURATION / 2)
 	rTimer.Reset()
-	// 250
-	<-rTimer.out
 	// 500
 
-	// We would expect the timer to be between 1000 and 1500 microseconds
+	// We would expect the timer to go off a full duration after the
+	// last reset, at 1000 microseconds
+	clock.Advance(DURATION - time.Nanosecond)
+	if fired(rTimer) {
+		t.Fatal("Timer fired even though it was reset")
+	}
 
-	t1 := time.Now()
+	clock.Advance(time.Nanosecond)
+	if !fired(rTimer) {
+		t.Errorf("Timer didn't fire a full duration after being reset")
+	}
+}
 
-	minDur := DURATION * 2
-	maxDur := DURATION * 3
+func TestResetTimerResetDropsSignal(t *testing.T) {
+	const DURATION = time.Millisecond
 
-	dur := t1.Sub(t0)
+	clock := NewFakeClock(time.Time{})
+	rTimer := NewResetTimerWithClock(clock, DURATION)
 
-	rTimer.Stop()
+	// A signal that hasn't been received yet is thrown away by a reset
+	clock.Advance(DURATION)
+	rTimer.Reset()
+
+	if fired(rTimer) {
+		t.Error("Signal from before the reset was still waiting")
+	}
 
-	if dur < minDur || dur > maxDur {
-		t.Errorf("Duration outside minimum of %v and max of %v; found %v", minDur, maxDur, dur)
+	// A stopped timer leaves nothing waiting on the clock
+	rTimer.Close()
+	if pending := clock.Pending(); pending != 0 {
+		t.Errorf("Expected nothing waiting on the clock, found %v", pending)
 	}
 }
 
@@ -76,18 +97,38 @@ func TestResetTimerChangeDuration(t *testing.T) {
 	const SMALL_DUR = time.Microsecond * 10
 	const LARGE_DUR = time.Microsecond * 500
 
-	// Create a timer with a ridiculously small
-	rTimer := NewResetTimer(SMALL_DUR)
+	// Create a timer with a ridiculously small duration
+	clock := NewFakeClock(time.Time{})
+	rTimer := NewResetTimerWithClock(clock, SMALL_DUR)
 
-	t0 := time.Now()
+	rTimer.SetDuration(LARGE_DUR)
 
-	rTimer.duration = LARGE_DUR
-	<-rTimer.out
-	<-rTimer.out
+	// The change only applies after the wait that's already going
+	clock.Advance(SMALL_DUR)
+	if !fired(rTimer) {
+		t.Fatal("Timer didn't finish the wait it started with")
+	}
+
+	clock.Advance(LARGE_DUR - time.Nanosecond)
+	if fired(rTimer) {
+		t.Fatal("Timer still used the small duration")
+	}
+
+	clock.Advance(time.Nanosecond)
+	if !fired(rTimer) {
+		t.Errorf("Changing duration failed, expected the timer to fire after %v", LARGE_DUR)
+	}
+}
 
-	t1 := time.Now()
+func TestResetTimerRealClock(t *testing.T) {
+	// Only makes sure the real clock is hooked up, the timing is
+	// covered by the tests above
+	rTimer := NewResetTimer(time.Millisecond)
+	defer rTimer.Close()
 
-	if dur := t1.Sub(t0); dur < LARGE_DUR {
-		t.Errorf("Changing duration failed expected duration over %v, found %v", LARGE_DUR, dur)
+	select {
+	case <-rTimer.out:
+	case <-time.After(time.Second):
+		t.Error("Timer never fired on the real clock")
 	}
 }
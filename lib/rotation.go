@@ -0,0 +1,439 @@
+package lib
+
+// A RotationSystem decides how tetrominos are oriented when they
+// spawn, what each of their rotations look like, and where they're
+// allowed to end up when a rotation doesn't fit in place. Different
+// communities expect different behavior, so the game lets you pick.
+type RotationSystem interface {
+	// Points a tetromino at this system's masks, in the orientation
+	// that it should spawn in
+	Spawn(tet *Tetromino)
+
+	// Attempts to rotate a tetromino on the board. Returns the
+	// tetromino in it's new position, and whether the rotation
+	// happened. If it didn't, the tetromino is left exactly as it was.
+	Rotate(tet ActiveTetromino, isLeft bool, board *Board) (ActiveTetromino, bool)
+}
+
+// Rotation systems by the names they're commonly known by. Handy for
+// picking one from a flag
+var RotationSystems = map[string]RotationSystem{
+	"srs":     SRSRotation{},
+	"ars":     ARSRotation{},
+	"nes":     NESRotation{},
+	"classic": ClassicRotation{},
+}
+
+// Returns a rotation system's masks for a shape. Shapes the system
+// doesn't have masks for, like registered ones, pivot the same way
+// they do under the classic rotation system.
+func shapeMasks(table [][]*[]bool, s Shape) []*[]bool {
+	if int(s) < len(table) {
+		return table[s]
+	}
+
+	return rotations[s]
+}
+
+// Returns the functions that apply a rotation in the given direction,
+// and undo it again
+func rotationFuncs(tet *Tetromino, isLeft bool) (func(), func()) {
+	if isLeft {
+		return tet.RotLeft, tet.RotRight
+	}
+
+	return tet.RotRight, tet.RotLeft
+}
+
+// The rotation system the game originally shipped with. Pieces spawn
+// as they're drawn in tetromino.go and pivot in place. If a rotation
+// pushes a piece outside of the board it gets clamped back inside,
+// and if it then overlaps anything the rotation is undone.
+type ClassicRotation struct{}
+
+func (ClassicRotation) Spawn(tet *Tetromino) {
+	tet.setMasks(rotations[tet.shape], 0)
+}
+
+func (ClassicRotation) Rotate(tet ActiveTetromino, isLeft bool, board *Board) (ActiveTetromino, bool) {
+	rotationFunc, rotationInverse := rotationFuncs(tet.Tetromino, isLeft)
+
+	// Apply the rotation
+	rotationFunc()
+
+	// Consider all edge cases
+	// 1. Pushing to the left on the X-Axis
+	// 2. Pushing up on the Y-Axis
+	// 3. Pushing to the right on the X-Axis
+	// 4. Pushing down on th Y- axis
+	minX := 0
+	minY := 0
+	maxX := board.Width() - 1
+	maxY := board.Height() - 1
+	for _, p := range tet.ListPositions() {
+		// Find minimum and maximum x and y values
+		if p.x > maxX {
+			maxX = p.x
+		} else if p.x < minX {
+			minX = p.x
+		}
+
+		if p.y < minY {
+			minY = p.y
+		} else if p.y > maxY {
+			maxY = p.y
+		}
+	}
+
+	// Shift in needed directions so it's in bounds, then check for
+	// any collisions
+	var deltaX, deltaY int
+	var yDir, xDir Direction
+
+	// The direction and delta we apply depends on which threshold
+	// was crossed.
+	if minX < 0 {
+		deltaX = 0 - minX
+		xDir = RIGHT
+	} else {
+		deltaX = maxX - (board.Width() - 1)
+		xDir = LEFT
+	}
+
+	if minY < 0 {
+		deltaY = 0 - minY
+		yDir = UP
+	} else {
+		deltaY = maxY - (board.Height() - 1)
+		yDir = DOWN
+	}
+
+	projectedTet := tet
+	for i := 0; i < deltaX; i++ {
+		projectedTet = projectedTet.Move(xDir)
+	}
+	for i := 0; i < deltaY; i++ {
+		projectedTet = projectedTet.Move(yDir)
+	}
+
+	if !projectedTet.fits(board) {
+		// Undo the rotation, the operation is idempotent
+		rotationInverse()
+		return tet, false
+	}
+
+	return projectedTet, true
+}
+
+// The masks used by the NES. The J, L and T pieces pivot around their
+// center, but the S, Z and line pieces only have two states, which
+// they flip between.
+var nesRotations = [][]*[]bool{
+	TET_SQUARE: pivotRotations(squareGrid),
+	TET_S: fixedRotations(
+		[]bool{
+			false, false, false,
+			false, true, true,
+			true, true, false,
+		},
+		[]bool{
+			false, true, false,
+			false, true, true,
+			false, false, true,
+		},
+		[]bool{
+			false, false, false,
+			false, true, true,
+			true, true, false,
+		},
+		[]bool{
+			false, true, false,
+			false, true, true,
+			false, false, true,
+		},
+	),
+	TET_Z: fixedRotations(
+		[]bool{
+			false, false, false,
+			true, true, false,
+			false, true, true,
+		},
+		[]bool{
+			false, false, true,
+			false, true, true,
+			false, true, false,
+		},
+		[]bool{
+			false, false, false,
+			true, true, false,
+			false, true, true,
+		},
+		[]bool{
+			false, false, true,
+			false, true, true,
+			false, true, false,
+		},
+	),
+	TET_L: pivotRotations(TetGrid{
+		grid: []bool{
+			false, false, false,
+			true, true, true,
+			true, false, false,
+		},
+		size: 3,
+	}),
+	TET_T: pivotRotations(tGrid),
+	TET_J: pivotRotations(TetGrid{
+		grid: []bool{
+			false, false, false,
+			true, true, true,
+			false, false, true,
+		},
+		size: 3,
+	}),
+	TET_LINE: fixedRotations(
+		[]bool{
+			false, false, false, false,
+			false, false, false, false,
+			true, true, true, true,
+			false, false, false, false,
+		},
+		[]bool{
+			false, false, true, false,
+			false, false, true, false,
+			false, false, true, false,
+			false, false, true, false,
+		},
+		[]bool{
+			false, false, false, false,
+			false, false, false, false,
+			true, true, true, true,
+			false, false, false, false,
+		},
+		[]bool{
+			false, false, true, false,
+			false, false, true, false,
+			false, false, true, false,
+			false, false, true, false,
+		},
+	),
+}
+
+// The Nintendo rotation system. Pieces spawn flat side up and a
+// rotation either fits where it is, or it doesn't happen at all.
+// There are no kicks.
+type NESRotation struct{}
+
+func (NESRotation) Spawn(tet *Tetromino) {
+	tet.setMasks(shapeMasks(nesRotations, tet.shape), 0)
+}
+
+func (NESRotation) Rotate(tet ActiveTetromino, isLeft bool, board *Board) (ActiveTetromino, bool) {
+	rotationFunc, rotationInverse := rotationFuncs(tet.Tetromino, isLeft)
+
+	rotationFunc()
+	if tet.fits(board) {
+		return tet, true
+	}
+
+	rotationInverse()
+	return tet, false
+}
+
+// The masks used by the Arika rotation system. Everything but the
+// line piece sits at the bottom of it's box, so pieces don't hop up
+// and down as they rotate. The S, Z and line pieces only have two
+// states.
+var arsRotations = [][]*[]bool{
+	TET_SQUARE: pivotRotations(squareGrid),
+	TET_S: fixedRotations(
+		[]bool{
+			false, false, false,
+			false, true, true,
+			true, true, false,
+		},
+		[]bool{
+			true, false, false,
+			true, true, false,
+			false, true, false,
+		},
+		[]bool{
+			false, false, false,
+			false, true, true,
+			true, true, false,
+		},
+		[]bool{
+			true, false, false,
+			true, true, false,
+			false, true, false,
+		},
+	),
+	TET_Z: fixedRotations(
+		[]bool{
+			false, false, false,
+			true, true, false,
+			false, true, true,
+		},
+		[]bool{
+			false, false, true,
+			false, true, true,
+			false, true, false,
+		},
+		[]bool{
+			false, false, false,
+			true, true, false,
+			false, true, true,
+		},
+		[]bool{
+			false, false, true,
+			false, true, true,
+			false, true, false,
+		},
+	),
+	TET_L: fixedRotations(
+		[]bool{
+			false, false, false,
+			true, true, true,
+			true, false, false,
+		},
+		[]bool{
+			false, true, false,
+			false, true, false,
+			false, true, true,
+		},
+		[]bool{
+			false, false, false,
+			false, false, true,
+			true, true, true,
+		},
+		[]bool{
+			true, true, false,
+			false, true, false,
+			false, true, false,
+		},
+	),
+	TET_T: fixedRotations(
+		[]bool{
+			false, false, false,
+			true, true, true,
+			false, true, false,
+		},
+		[]bool{
+			false, true, false,
+			false, true, true,
+			false, true, false,
+		},
+		[]bool{
+			false, false, false,
+			false, true, false,
+			true, true, true,
+		},
+		[]bool{
+			false, true, false,
+			true, true, false,
+			false, true, false,
+		},
+	),
+	TET_J: fixedRotations(
+		[]bool{
+			false, false, false,
+			true, true, true,
+			false, false, true,
+		},
+		[]bool{
+			false, true, true,
+			false, true, false,
+			false, true, false,
+		},
+		[]bool{
+			false, false, false,
+			true, false, false,
+			true, true, true,
+		},
+		[]bool{
+			false, true, false,
+			false, true, false,
+			true, true, false,
+		},
+	),
+	TET_LINE: fixedRotations(
+		[]bool{
+			false, false, false, false,
+			true, true, true, true,
+			false, false, false, false,
+			false, false, false, false,
+		},
+		[]bool{
+			false, false, true, false,
+			false, false, true, false,
+			false, false, true, false,
+			false, false, true, false,
+		},
+		[]bool{
+			false, false, false, false,
+			true, true, true, true,
+			false, false, false, false,
+			false, false, false, false,
+		},
+		[]bool{
+			false, false, true, false,
+			false, false, true, false,
+			false, false, true, false,
+			false, false, true, false,
+		},
+	),
+}
+
+// The Arika rotation system, as seen in the TGM series. When a
+// rotation doesn't fit in place, the piece tries one tile to the
+// right and then one tile to the left. The line piece never kicks.
+type ARSRotation struct{}
+
+func (ARSRotation) Spawn(tet *Tetromino) {
+	tet.setMasks(shapeMasks(arsRotations, tet.shape), 0)
+}
+
+func (ARSRotation) Rotate(tet ActiveTetromino, isLeft bool, board *Board) (ActiveTetromino, bool) {
+	rotationFunc, rotationInverse := rotationFuncs(tet.Tetromino, isLeft)
+
+	rotationFunc()
+	if tet.fits(board) {
+		return tet, true
+	}
+
+	if tet.shape != TET_LINE && !arsCenterBlocked(tet, board) {
+		for _, dir := range []Direction{RIGHT, LEFT} {
+			if projectedTet := tet.Move(dir); projectedTet.fits(board) {
+				return projectedTet, true
+			}
+		}
+	}
+
+	rotationInverse()
+	return tet, false
+}
+
+// The J, L and T pieces aren't allowed to kick when the first tile
+// that's in the way, reading the box from the top left, is in the
+// center column. This stops them from climbing out of holes they
+// shouldn't be able to.
+func arsCenterBlocked(tet ActiveTetromino, board *Board) bool {
+	if tet.shape != TET_J && tet.shape != TET_L && tet.shape != TET_T {
+		return false
+	}
+
+	mask := tet.GetMask()
+	for dy := 0; dy < tet.size; dy++ {
+		for dx := 0; dx < tet.size; dx++ {
+			if !mask[dy*tet.size+dx] {
+				continue
+			}
+
+			if board.Blocked(tet.x+dx, tet.y-dy) {
+				return dx == 1
+			}
+		}
+	}
+
+	return false
+}
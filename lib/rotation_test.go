@@ -0,0 +1,151 @@
+package lib
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Spawns a tetromino with the given rotation system, at a position
+func spawnTet(rs RotationSystem, s Shape, x, y int) ActiveTetromino {
+	tet := NewTet(s)
+	rs.Spawn(tet)
+
+	active := NewActiveTet(tet)
+	active.x, active.y = x, y
+	return active
+}
+
+// Rotating four times in the same direction on an empty board should
+// always bring a tetromino back to where it started, for any system
+func TestRotationSystemsFullCircle(t *testing.T) {
+	for name, rs := range RotationSystems {
+		for _, s := range shapes {
+			board := &Board{}
+			tet := spawnTet(rs, s, 3, 10)
+
+			initPositions := tet.ListPositions()
+
+			for _, isLeft := range []bool{true, false} {
+				for i := 0; i < 4; i++ {
+					var ok bool
+					tet, ok = rs.Rotate(tet, isLeft, board)
+					if !ok {
+						t.Errorf("%v: shape %v couldn't rotate on an empty board", name, s)
+					}
+				}
+
+				if !reflect.DeepEqual(initPositions, tet.ListPositions()) {
+					t.Errorf("%v: shape %v isn't the same after four rotations. Expected %v, found %v",
+						name, s, initPositions, tet.ListPositions())
+				}
+			}
+		}
+	}
+}
+
+func TestRotationSystemSpawns(t *testing.T) {
+	// The T piece is the easiest way to tell the systems apart. SRS
+	// points it up, everything else points it down
+	pointingUp := []bool{
+		false, true, false,
+		true, true, true,
+		false, false, false,
+	}
+	pointingDown := []bool{
+		false, false, false,
+		true, true, true,
+		false, true, false,
+	}
+
+	expected := map[string][]bool{
+		"srs":     pointingUp,
+		"ars":     pointingDown,
+		"nes":     pointingDown,
+		"classic": pointingDown,
+	}
+
+	for name, rs := range RotationSystems {
+		tet := NewTet(TET_T)
+		rs.Spawn(tet)
+
+		if mask := tet.GetMask(); !reflect.DeepEqual(mask, expected[name]) {
+			t.Errorf("%v: unexpected spawn mask for T: %v", name, mask)
+		}
+	}
+}
+
+func TestClassicRotationClamps(t *testing.T) {
+	board := &Board{}
+
+	// A vertical line against the right wall
+	tet := spawnTet(ClassicRotation{}, TET_LINE, BOARD_WIDTH-2, 10)
+
+	tet, ok := ClassicRotation{}.Rotate(tet, true, board)
+	if !ok {
+		t.Fatal("Line couldn't rotate against the wall")
+	}
+
+	for _, p := range tet.ListPositions() {
+		if p.x >= BOARD_WIDTH {
+			t.Errorf("Line wasn't clamped back inside the board: %v", p)
+		}
+	}
+}
+
+func TestNESRotationNoKicks(t *testing.T) {
+	board := &Board{}
+	rs := NESRotation{}
+
+	// The vertical line sits in the third column of it's box, so this
+	// is flush against the right wall
+	tet := spawnTet(rs, TET_LINE, 0, 10)
+	tet.Tetromino.RotLeft()
+	tet.x = BOARD_WIDTH - 3
+
+	initPositions := tet.ListPositions()
+	tet, ok := rs.Rotate(tet, true, board)
+
+	if ok || !reflect.DeepEqual(initPositions, tet.ListPositions()) {
+		t.Errorf("Line rotated against the wall, but NES has no kicks. Found %v", tet.ListPositions())
+	}
+}
+
+func TestARSRotationKicks(t *testing.T) {
+	rs := ARSRotation{}
+
+	// A T pointing right, flush against the left wall. Laying it flat
+	// doesn't fit, so it should kick one tile to the right
+	board := &Board{}
+	tet := spawnTet(rs, TET_T, -1, 10)
+	tet.Tetromino.RotLeft()
+
+	tet, ok := rs.Rotate(tet, false, board)
+	if !ok || tet.x != 0 {
+		t.Errorf("T didn't kick off the wall. Rotated: %v, x: %v", ok, tet.x)
+	}
+
+	// A flat T with a tile above it's center. The rotation would be
+	// blocked in the center column first, so no kick is allowed even
+	// though there's room to the right
+	board = &Board{}
+	tet = spawnTet(rs, TET_T, 3, 10)
+	board.SetTile(C1, 4, 10)
+
+	initPositions := tet.ListPositions()
+	tet, ok = rs.Rotate(tet, false, board)
+	if ok || !reflect.DeepEqual(initPositions, tet.ListPositions()) {
+		t.Errorf("T kicked even though it was blocked in the center column. Found %v", tet.ListPositions())
+	}
+
+	// The line piece never kicks
+	board = &Board{}
+	tet = spawnTet(rs, TET_LINE, 0, 10)
+	tet.Tetromino.RotLeft()
+	tet.x = BOARD_WIDTH - 3
+
+	initPositions = tet.ListPositions()
+	tet, ok = rs.Rotate(tet, true, board)
+	if ok || !reflect.DeepEqual(initPositions, tet.ListPositions()) {
+		t.Errorf("Line kicked off the wall, but ARS doesn't kick lines. Found %v", tet.ListPositions())
+	}
+}
@@ -0,0 +1,115 @@
+package lib
+
+// The rules for where tetrominos come in, and when the stack has
+// grown too high and the game is over. A game is always over when
+// there's no room for the next tetromino to come in, which is known
+// as a block out.
+type Ruleset struct {
+	// Centered tetrominos come in the middle of the board, leaning left
+	// when they can't be exactly in the middle, with their bottom row
+	// just above the visible rows. Otherwise the top left of their mask
+	// comes in SpawnX columns right of the middle column and SpawnY
+	// rows above the visible rows, no matter their shape.
+	Centered       bool
+	SpawnX, SpawnY int
+	// A lock out ends the game when a tetromino locks entirely above the
+	// visible rows, and a partial lock out when any of it does. Neither
+	// happens if the tetromino clears any lines. The rest of the board
+	// above the visible rows is a buffer tetrominos come in through.
+	LockOut        bool
+	PartialLockOut bool
+	// Moves particular shapes from where they'd otherwise come in.
+	// Definition files set these for their pieces
+	Offsets map[Shape]Position `json:"-"`
+}
+
+// The rules from the Tetris Guideline. Tetrominos come in centered,
+// in the two rows above the visible ones, which are rows 21 and 22
+// counting from one on a default board.
+func GuidelineRuleset() Ruleset {
+	return Ruleset{
+		Centered: true,
+		LockOut:  true,
+	}
+}
+
+// The rules this game has always used. Every tetromino comes in at the
+// same place, which is STARTING_X and STARTING_Y on a default board,
+// and the game is over as soon as one locks with any part of it above
+// the visible rows.
+func ClassicRuleset() Ruleset {
+	return Ruleset{
+		SpawnY:         1,
+		PartialLockOut: true,
+	}
+}
+
+// Rulesets by name. Handy for picking one from a flag
+var Rulesets = map[string]Ruleset{
+	"guideline": GuidelineRuleset(),
+	"classic":   ClassicRuleset(),
+}
+
+// Sets the rules for spawning and topping out. Games use the classic
+// rules by default
+func WithRuleset(rules Ruleset) GameOption {
+	return func(game *Game) {
+		game.rules = rules
+	}
+}
+
+// Returns where a tetromino comes in on a board, given it's already
+// facing the way it spawns
+func (rules Ruleset) SpawnPosition(tet *Tetromino, board *Board) Position {
+	p := rules.spawnPosition(tet, board)
+	if offset, ok := rules.Offsets[tet.shape]; ok {
+		p.x += offset.x
+		p.y += offset.y
+	}
+
+	return p
+}
+
+func (rules Ruleset) spawnPosition(tet *Tetromino, board *Board) Position {
+	if !rules.Centered {
+		return Position{(board.Width()-1)/2 + rules.SpawnX, board.Visible() + rules.SpawnY}
+	}
+
+	// Find the part of the mask that's actually filled in
+	mask := tet.GetMask()
+	left, right, bottom := tet.size, -1, -1
+	for dy := 0; dy < tet.size; dy++ {
+		for dx := 0; dx < tet.size; dx++ {
+			if !mask[dy*tet.size+dx] {
+				continue
+			}
+
+			if dx < left {
+				left = dx
+			}
+			if dx > right {
+				right = dx
+			}
+			bottom = dy
+		}
+	}
+
+	width := right - left + 1
+	x := (board.Width() - width) / 2
+
+	// Rows count down from the top of the mask
+	return Position{x - left, board.Visible() + bottom}
+}
+
+// Returns true if a tetromino locked in the given position tops the
+// game out under these rules. It's assumed no lines were cleared.
+func (rules Ruleset) lockedOut(ps []Position, board *Board) bool {
+	var above int
+	for _, p := range ps {
+		if p.y >= board.Visible() {
+			above++
+		}
+	}
+
+	return rules.PartialLockOut && above > 0 || rules.LockOut && above == len(ps)
+}
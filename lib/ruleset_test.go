@@ -0,0 +1,113 @@
+package lib
+
+import (
+	"testing"
+)
+
+// Returns the columns and rows a list of positions spans
+func bounds(ps []Position) (left, right, bottom, top int) {
+	left, right, bottom, top = BOARD_WIDTH, -1, BOARD_HEIGHT, -1
+	for _, p := range ps {
+		if p.x < left {
+			left = p.x
+		}
+		if p.x > right {
+			right = p.x
+		}
+		if p.y < bottom {
+			bottom = p.y
+		}
+		if p.y > top {
+			top = p.y
+		}
+	}
+
+	return left, right, bottom, top
+}
+
+func TestGuidelineSpawn(t *testing.T) {
+	rules := GuidelineRuleset()
+
+	// Columns counting from zero. The three wide tetrominos lean left
+	tests := map[Shape][2]int{
+		TET_SQUARE: {4, 5},
+		TET_S:      {3, 5},
+		TET_Z:      {3, 5},
+		TET_L:      {3, 5},
+		TET_T:      {3, 5},
+		TET_J:      {3, 5},
+		TET_LINE:   {3, 6},
+	}
+
+	for _, rotation := range []RotationSystem{SRSRotation{}, ARSRotation{}} {
+		for shape, cols := range tests {
+			board := &Board{}
+			ctl := NewBoardControllerWithRules(board, NewTet(shape), rotation, rules)
+
+			left, right, bottom, top := bounds(ctl.tet.ListPositions())
+			if left != cols[0] || right != cols[1] {
+				t.Errorf("%T shape %v: expected columns %v, found %v to %v", rotation, shape, cols, left, right)
+			}
+
+			// Rows 21 and 22, counting from one
+			if bottom != GAMEOVER_LINE || top > GAMEOVER_LINE+1 {
+				t.Errorf("%T shape %v: expected to be in rows 20 and 21, found %v to %v", rotation, shape, bottom, top)
+			}
+		}
+	}
+}
+
+func TestRulesetLockOut(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules Ruleset
+		// How far above the visible rows the bottom of the tetromino is
+		above    int
+		gameover bool
+	}{
+		{"guideline partly above", GuidelineRuleset(), -1, false},
+		{"guideline above", GuidelineRuleset(), 0, true},
+		{"classic partly above", ClassicRuleset(), -1, true},
+		{"classic below", ClassicRuleset(), -2, false},
+		{"block out only", Ruleset{Centered: true}, 0, false},
+	}
+
+	for _, test := range tests {
+		// A tower for the square to land on
+		board := &Board{}
+		for y := 0; y < GAMEOVER_LINE+test.above; y++ {
+			board.SetTile(C1, 0, y)
+		}
+
+		ctl := NewBoardControllerWithRules(board, NewTet(TET_SQUARE), ClassicRotation{}, test.rules)
+		ctl.tet.Position = Position{0, GAMEOVER_LINE + test.above + 1}
+		ctl.Tick(MOVE_SLAM, NewTet(TET_SQUARE))
+
+		if ctl.isGameover != test.gameover {
+			t.Errorf("%v: expected gameover to be %v", test.name, test.gameover)
+		}
+	}
+}
+
+func TestRulesetBlockOut(t *testing.T) {
+	rules := GuidelineRuleset()
+
+	// Fill in where the next tetromino comes in
+	board := &Board{}
+	next := NewTet(TET_T)
+	ctl := NewBoardControllerWithRules(board, NewTet(TET_SQUARE), SRSRotation{}, rules)
+	SRSRotation{}.Spawn(next)
+	for _, p := range (ActiveTetromino{next, rules.SpawnPosition(next, board)}).ListPositions() {
+		board.SetTile(C1, p.x, p.y)
+	}
+
+	ctl.Move(LEFT)
+	ctl.Move(LEFT)
+	ctl.Move(LEFT)
+	ctl.Move(LEFT)
+	ctl.Tick(MOVE_SLAM, next)
+
+	if !ctl.isGameover {
+		t.Error("Expected a block out when there's no room for the next tetromino")
+	}
+}
@@ -0,0 +1,494 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// The version of the save format written by this version of the game.
+// It goes up whenever a change means older games can't read new saves,
+// and saves from any other version are refused.
+const SAVE_VERSION = 1
+
+// Binary saves start with this, followed by the version as a big
+// endian uint16
+const SAVE_MAGIC = "TSAV"
+
+// The most shapes a saved game can have dealt. Loading deals them all
+// again, so a save can't make that take forever
+const maxDealt = 1 << 20
+
+// Everything needed to pick a game back up where it was left. How the
+// game is played, like it's rotation system, scoring and delays, isn't
+// saved. Those come from the options the game being loaded into was
+// made with, and enough about them is saved to refuse a game made with
+// other options.
+//
+// The randomizer isn't saved either, only the seed it was made with
+// and how many shapes it has dealt. Randomizers are seeded, so it's
+// made again and skipped ahead to the same place. The last shapes it
+// deals have to be the ones in the preview, or it's not the same
+// randomizer.
+type savedGame struct {
+	Version  int           `json:"version"`
+	Settings savedSettings `json:"settings"`
+
+	Seed  int64 `json:"seed"`
+	Dealt int   `json:"dealt"`
+
+	StartingLevel int `json:"startingLevel"`
+	Lines         int `json:"lines"`
+	Score         int `json:"score"`
+	Ticks         int `json:"ticks"`
+
+	Board       savedBoard     `json:"board"`
+	Active      savedActiveTet `json:"active"`
+	Preview     []savedTet     `json:"preview"`
+	Held        *savedTet      `json:"held,omitempty"`
+	HoldUsed    bool           `json:"holdUsed"`
+	Gameover    bool           `json:"gameover"`
+	LastRotated bool           `json:"lastRotated"`
+	LastKick    [2]int         `json:"lastKick"`
+	Spawned     int            `json:"spawned"`
+
+	Phase    Phase      `json:"phase"`
+	Clearing []int      `json:"clearing,omitempty"`
+	Buffered []Movement `json:"buffered,omitempty"`
+
+	// For stepped games, the frames stepped so far and since the
+	// tetromino last fell. Both are zero for games that are played
+	Frame   int `json:"frame"`
+	Gravity int `json:"gravity"`
+
+	// Whatever the scorer remembers, for scorers that implement
+	// json.Marshaler and json.Unmarshaler
+	Scorer json.RawMessage `json:"scorer,omitempty"`
+}
+
+// The options a game was made with, as far as they can be told apart.
+// Rotation systems and scorers are only known by their type, and
+// randomizers by their name in Randomizers, or the name of the function
+// that makes them if they aren't in there. The shapes are known by a
+// hash of every registered shape, how the rotation system turns and
+// kicks it, and where the rules spawn it. The rest of the rules are
+// kept as JSON. The board size comes from the save, so it doesn't have
+// to match.
+type savedSettings struct {
+	Rotation       string        `json:"rotation"`
+	Scorer         string        `json:"scorer"`
+	Randomizer     string        `json:"randomizer"`
+	Rules          string        `json:"rules"`
+	Shapes         uint32        `json:"shapes"`
+	Preview        int           `json:"preview"`
+	Frame          time.Duration `json:"frame"`
+	LockDelay      time.Duration `json:"lockDelay"`
+	MaxResets      int           `json:"maxResets"`
+	LineClearDelay time.Duration `json:"lineClearDelay"`
+	EntryDelay     time.Duration `json:"entryDelay"`
+}
+
+func (game *Game) settings() savedSettings {
+	// Rulesets are plain values, apart from the offsets which aren't
+	// encoded
+	rules, _ := json.Marshal(game.rules)
+
+	return savedSettings{
+		Rotation:       fmt.Sprintf("%T", game.rotation),
+		Scorer:         fmt.Sprintf("%T", game.scorer),
+		Randomizer:     randomizerName(game.newRandomizer),
+		Rules:          string(rules),
+		Shapes:         game.shapesHash(),
+		Preview:        len(game.preview),
+		Frame:          game.frame,
+		LockDelay:      game.lockDelay,
+		MaxResets:      game.maxResets,
+		LineClearDelay: game.lineClearDelay,
+		EntryDelay:     game.entryDelay,
+	}
+}
+
+// Functions can't be compared, so randomizers are told apart by where
+// their function's code is
+func randomizerName(newRandomizer func(seed int64) Randomizer) string {
+	pc := reflect.ValueOf(newRandomizer).Pointer()
+	for name, other := range Randomizers {
+		if reflect.ValueOf(other).Pointer() == pc {
+			return name
+		}
+	}
+
+	if fn := runtime.FuncForPC(pc); fn != nil {
+		return fn.Name()
+	}
+	return ""
+}
+
+func (game *Game) shapesHash() uint32 {
+	// Only rotation systems from definition files have their own kicks,
+	// the rest are known by their type
+	defined, _ := game.rotation.(DefinedRotation)
+
+	h := fnv.New32a()
+	for _, s := range Shapes() {
+		tet := NewTet(s)
+		game.rotation.Spawn(tet)
+		offset := game.rules.Offsets[s]
+		fmt.Fprint(h, s, tet.size, offset.x, offset.y)
+
+		for _, mask := range tet.masks {
+			for _, filled := range *mask {
+				if filled {
+					h.Write([]byte{1})
+				} else {
+					h.Write([]byte{0})
+				}
+			}
+		}
+
+		if defined.kicks != nil {
+			fmt.Fprint(h, defined.kicks[s])
+		}
+	}
+
+	return h.Sum32()
+}
+
+// Returns the names of the settings that are different, using their
+// names in the JSON
+func (s savedSettings) diff(other savedSettings) []string {
+	var names []string
+	a, b := reflect.ValueOf(s), reflect.ValueOf(other)
+	for i := 0; i < a.NumField(); i++ {
+		if !reflect.DeepEqual(a.Field(i).Interface(), b.Field(i).Interface()) {
+			names = append(names, a.Type().Field(i).Tag.Get("json"))
+		}
+	}
+
+	return names
+}
+
+// The tiles are listed a row at a time, starting from the bottom left
+type savedBoard struct {
+	Width   int         `json:"width"`
+	Visible int         `json:"visible"`
+	Tiles   []TileColor `json:"tiles"`
+}
+
+// The rotation is the index into the rotation system's masks, which
+// counts left rotations from the first mask
+type savedTet struct {
+	Shape    Shape `json:"shape"`
+	Rotation int   `json:"rotation"`
+}
+
+type savedActiveTet struct {
+	Shape    Shape `json:"shape"`
+	Rotation int   `json:"rotation"`
+	X        int   `json:"x"`
+	Y        int   `json:"y"`
+}
+
+func saveTet(tet *Tetromino) savedTet {
+	return savedTet{Shape: tet.shape, Rotation: tet.rotationIdx}
+}
+
+// Makes the tetromino again, turned the way it was with the game's
+// rotation system
+func (game *Game) loadTet(saved savedTet) (*Tetromino, error) {
+	tet, err := TryNewTet(saved.Shape)
+	if err != nil {
+		return nil, err
+	}
+
+	game.rotation.Spawn(tet)
+	if saved.Rotation < 0 || saved.Rotation >= len(tet.masks) {
+		return nil, fmt.Errorf("shape %v has no rotation %v", saved.Shape, saved.Rotation)
+	}
+	tet.setMasks(tet.masks, saved.Rotation)
+
+	return tet, nil
+}
+
+func (game *Game) save() (*savedGame, error) {
+	ctl := game.controller
+	saved := &savedGame{
+		Version:       SAVE_VERSION,
+		Settings:      game.settings(),
+		Seed:          game.seed,
+		Dealt:         game.dealt,
+		StartingLevel: game.startingLevel,
+		Lines:         game.lines,
+		Score:         game.score,
+		Ticks:         game.ticks,
+		Board: savedBoard{
+			Width:   ctl.board.Width(),
+			Visible: ctl.board.Visible(),
+		},
+		Active: savedActiveTet{
+			Shape:    ctl.tet.shape,
+			Rotation: ctl.tet.rotationIdx,
+			X:        ctl.tet.x,
+			Y:        ctl.tet.y,
+		},
+		HoldUsed:    game.holdUsed,
+		Gameover:    ctl.isGameover,
+		LastRotated: ctl.lastRotated,
+		LastKick:    [2]int{ctl.lastKick.x, ctl.lastKick.y},
+		Spawned:     ctl.spawned,
+		Phase:       game.phase,
+		Clearing:    append([]int(nil), game.clearing...),
+		Buffered:    append([]Movement(nil), game.buffered...),
+		Frame:       game.steps.frame,
+		Gravity:     game.steps.gravity,
+	}
+
+	for y := 0; y < ctl.board.Height(); y++ {
+		for x := 0; x < ctl.board.Width(); x++ {
+			saved.Board.Tiles = append(saved.Board.Tiles, ctl.board.GetTile(x, y))
+		}
+	}
+
+	for _, tet := range game.preview {
+		saved.Preview = append(saved.Preview, saveTet(tet))
+	}
+	if game.heldTet != nil {
+		held := saveTet(game.heldTet)
+		saved.Held = &held
+	}
+
+	if m, ok := game.scorer.(json.Marshaler); ok {
+		scorer, err := m.MarshalJSON()
+		if err != nil {
+			return nil, fmt.Errorf("saving scorer: %w", err)
+		}
+		saved.Scorer = scorer
+	}
+
+	return saved, nil
+}
+
+// Puts the game in the saved state. Everything is checked before the
+// game is touched, so it's left as it was if the save is no good.
+func (game *Game) load(saved *savedGame) error {
+	if saved.Version != SAVE_VERSION {
+		return fmt.Errorf("%w: version %v, only version %v can be loaded", ErrInvalidSave, saved.Version, SAVE_VERSION)
+	}
+	if diff := saved.Settings.diff(game.settings()); len(diff) > 0 {
+		return fmt.Errorf("%w: the game was saved with a different %v", ErrInvalidSave, strings.Join(diff, ", "))
+	}
+
+	// The board has to be made at the saved size, or the tiles won't
+	// line up
+	sb := saved.Board
+	board := NewBoard(sb.Width, sb.Visible)
+	if board.Width() != sb.Width || board.Visible() != sb.Visible {
+		return fmt.Errorf("%w: a %vx%v board is too small", ErrInvalidSave, sb.Width, sb.Visible)
+	}
+	if len(sb.Tiles) != board.Width()*board.Height() {
+		return fmt.Errorf("%w: expected %v tiles on the board, found %v", ErrInvalidSave, board.Width()*board.Height(), len(sb.Tiles))
+	}
+	for i, tile := range sb.Tiles {
+		if err := board.TrySetTile(tile, i%board.Width(), i/board.Width()); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidSave, err)
+		}
+	}
+
+	active, err := game.loadTet(savedTet{saved.Active.Shape, saved.Active.Rotation})
+	if err != nil {
+		return fmt.Errorf("%w: active tetromino: %v", ErrInvalidSave, err)
+	}
+	activePos := Position{saved.Active.X, saved.Active.Y}
+
+	if len(saved.Preview) != len(game.preview) {
+		return fmt.Errorf("%w: saved with a preview of %v, the game has %v", ErrInvalidSave, len(saved.Preview), len(game.preview))
+	}
+	preview := make([]*Tetromino, len(saved.Preview))
+	for i, tet := range saved.Preview {
+		if preview[i], err = game.loadTet(tet); err != nil {
+			return fmt.Errorf("%w: preview: %v", ErrInvalidSave, err)
+		}
+	}
+
+	var held *Tetromino
+	if saved.Held != nil {
+		if held, err = game.loadTet(*saved.Held); err != nil {
+			return fmt.Errorf("%w: held tetromino: %v", ErrInvalidSave, err)
+		}
+	}
+
+	if saved.Phase < PHASE_ACTIVE || saved.Phase > PHASE_ENTRY {
+		return fmt.Errorf("%w: unknown phase %v", ErrInvalidSave, int(saved.Phase))
+	}
+	// Outside of the active phase it's the tetromino that was locked
+	// last, which is on the board already
+	if saved.Phase == PHASE_ACTIVE && !saved.Gameover && !(ActiveTetromino{active, activePos}).fits(board) {
+		return fmt.Errorf("%w: the active tetromino at %v,%v doesn't fit on the board", ErrInvalidSave, activePos.x, activePos.y)
+	}
+	for _, row := range saved.Clearing {
+		if row < 0 || row >= board.Height() {
+			return fmt.Errorf("%w: clearing row %v isn't on the board", ErrInvalidSave, row)
+		}
+	}
+	for _, move := range saved.Buffered {
+		if !move.Valid() {
+			return fmt.Errorf("%w: buffered %v", ErrInvalidSave, move)
+		}
+	}
+	// Each tick deals at most two shapes, when a tetromino locks and
+	// the next one goes straight into hold
+	if saved.Ticks < 0 || saved.Dealt < 1+len(preview) || saved.Dealt > maxDealt || saved.Dealt > 1+len(preview)+2*saved.Ticks {
+		return fmt.Errorf("%w: %v shapes dealt in %v ticks", ErrInvalidSave, saved.Dealt, saved.Ticks)
+	}
+	if saved.Frame < 0 || saved.Gravity < 0 || saved.Gravity > saved.Frame {
+		return fmt.Errorf("%w: %v frames since the tetromino fell, in %v frames", ErrInvalidSave, saved.Gravity, saved.Frame)
+	}
+
+	// Deal the same shapes again, so the randomizer carries on from
+	// where it was. The last ones dealt are the preview
+	randomizer := game.newRandomizer(saved.Seed)
+	last := make([]Shape, len(preview))
+	for i := 0; i < saved.Dealt; i++ {
+		last[i%len(last)] = randomizer.Next()
+	}
+	for i, tet := range preview {
+		if dealt := last[(saved.Dealt-len(preview)+i)%len(last)]; dealt != tet.shape {
+			return fmt.Errorf("%w: the randomizer deals a %v where the preview has a %v", ErrInvalidSave, dealt, tet.shape)
+		}
+	}
+
+	if len(saved.Scorer) > 0 {
+		u, ok := game.scorer.(json.Unmarshaler)
+		if !ok {
+			return fmt.Errorf("%w: the game's scorer %T can't be loaded", ErrInvalidSave, game.scorer)
+		}
+		if err := u.UnmarshalJSON(saved.Scorer); err != nil {
+			return fmt.Errorf("%w: scorer: %v", ErrInvalidSave, err)
+		}
+	}
+
+	game.seed = saved.Seed
+	game.randomizer = randomizer
+	game.dealt = saved.Dealt
+
+	game.startingLevel = saved.StartingLevel
+	game.lines = saved.Lines
+	game.score = saved.Score
+	game.ticks = saved.Ticks
+	game.width, game.visible = board.Width(), board.Visible()
+	game.preview = preview
+	game.heldTet = held
+	game.holdUsed = saved.HoldUsed
+	game.phase = saved.Phase
+	game.clearing = saved.Clearing
+	game.buffered = saved.Buffered
+	game.lockArmed, game.lockResets = false, 0
+	game.steps = stepState{frame: saved.Frame, gravity: saved.Gravity}
+
+	game.controller = &BoardController{
+		board:       board,
+		tet:         ActiveTetromino{active, activePos},
+		rotation:    game.rotation,
+		rules:       game.rules,
+		isGameover:  saved.Gameover,
+		lastRotated: saved.LastRotated,
+		lastKick:    Position{saved.LastKick[0], saved.LastKick[1]},
+		spawned:     saved.Spawned,
+	}
+
+	return nil
+}
+
+// Encodes the whole state of the game as JSON, see savedGame for
+// what's in it
+func (game *Game) MarshalJSON() ([]byte, error) {
+	saved, err := game.save()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(saved)
+}
+
+// Picks up a game saved with MarshalJSON. The game should be made with
+// the same options as the one that was saved. Returns ErrInvalidSave
+// if the save can't be loaded, and leaves the game as it was.
+func (game *Game) UnmarshalJSON(data []byte) error {
+	var saved savedGame
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSave, err)
+	}
+
+	return game.load(&saved)
+}
+
+// Encodes the whole state of the game, after a header with
+// SAVE_MAGIC and the version
+func (game *Game) MarshalBinary() ([]byte, error) {
+	saved, err := game.save()
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteString(SAVE_MAGIC)
+	binary.Write(buf, binary.BigEndian, uint16(SAVE_VERSION))
+	if err := gob.NewEncoder(buf).Encode(saved); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Picks up a game saved with MarshalBinary, see UnmarshalJSON
+func (game *Game) UnmarshalBinary(data []byte) error {
+	if !bytes.HasPrefix(data, []byte(SAVE_MAGIC)) || len(data) < len(SAVE_MAGIC)+2 {
+		return fmt.Errorf("%w: not a saved game", ErrInvalidSave)
+	}
+	data = data[len(SAVE_MAGIC):]
+
+	version := binary.BigEndian.Uint16(data)
+	if version != SAVE_VERSION {
+		return fmt.Errorf("%w: version %v, only version %v can be loaded", ErrInvalidSave, version, SAVE_VERSION)
+	}
+
+	var saved savedGame
+	if err := gob.NewDecoder(bytes.NewReader(data[2:])).Decode(&saved); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSave, err)
+	}
+
+	return game.load(&saved)
+}
+
+// Starts the timer for the delay the game is in, for games that were
+// loaded partway through one. Without a timer for it, the delay is
+// over straight away. Delays that were partly over when the game was
+// saved start over, and so does the lock delay.
+func (game *Game) resumeDelay() {
+	switch game.phase {
+	case PHASE_ACTIVE:
+		game.updateLockDelay(false, true)
+	case PHASE_LINE_CLEAR:
+		if game.clearTimer != nil {
+			game.clearTimer.Reset()
+			return
+		}
+
+		game.clearing = nil
+		game.startDelay(nil)
+	case PHASE_ENTRY:
+		if game.entryTimer != nil {
+			game.entryTimer.Reset()
+			return
+		}
+
+		game.spawnNext()
+	}
+}
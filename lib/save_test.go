@@ -0,0 +1,347 @@
+package lib
+
+import (
+	"encoding/json"
+	"errors"
+	"image/color"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Plays some random moves, holds included, the same way each time
+func playRandom(game *Game, r *rand.Rand, ticks int) {
+	for i := 0; i < ticks && !game.IsGameover(); i++ {
+		game.Tick(Movement(r.Intn(int(maxMovement) + 1)))
+	}
+}
+
+func sameGame(t *testing.T, name string, expected, found *Game) {
+	t.Helper()
+
+	a, b := expected.Snap(), found.Snap()
+	if !reflect.DeepEqual(a, b) {
+		t.Fatalf("%v: loaded game doesn't match.\nExpected: %+v\nFound:    %+v", name, a, b)
+	}
+}
+
+func TestGameSaveLoad(t *testing.T) {
+	encodings := []struct {
+		name      string
+		marshal   func(*Game) ([]byte, error)
+		unmarshal func(*Game, []byte) error
+	}{
+		{"binary", (*Game).MarshalBinary, (*Game).UnmarshalBinary},
+		{"json", (*Game).MarshalJSON, (*Game).UnmarshalJSON},
+	}
+
+	for _, enc := range encodings {
+		for _, name := range []string{"7bag", "tgm", "nes"} {
+			opts := []GameOption{WithRandomizer(Randomizers[name]), WithBoardSize(8, 16)}
+			game := NewGame(42, 3, opts...)
+			r := rand.New(rand.NewSource(1))
+			playRandom(game, r, 40)
+			if game.IsGameover() {
+				t.Fatalf("%v %v: expected the game to still be going", enc.name, name)
+			}
+
+			data, err := enc.marshal(game)
+			if err != nil {
+				t.Fatalf("%v %v: %v", enc.name, name, err)
+			}
+
+			// The seed and level the game is made with don't matter
+			loaded := NewGame(0, 1, opts...)
+			if err := enc.unmarshal(loaded, data); err != nil {
+				t.Fatalf("%v %v: %v", enc.name, name, err)
+			}
+			sameGame(t, enc.name+" "+name, game, loaded)
+
+			// Both carry on the same, so the randomizer picked up where
+			// it left off
+			seed := r.Int63()
+			playRandom(game, rand.New(rand.NewSource(seed)), 40)
+			playRandom(loaded, rand.New(rand.NewSource(seed)), 40)
+			sameGame(t, enc.name+" "+name+" after playing on", game, loaded)
+		}
+	}
+}
+
+func TestGameSaveJSONVersion(t *testing.T) {
+	data, err := json.Marshal(NewGame(0, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var saved map[string]interface{}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if saved["version"] != float64(SAVE_VERSION) {
+		t.Errorf("Expected the save to have version %v, found %v", SAVE_VERSION, saved["version"])
+	}
+}
+
+func TestGameLoadInvalid(t *testing.T) {
+	game := NewGame(7, 1)
+	game.Tick(MOVE_SLAM)
+
+	good, err := game.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	binaryGood, err := game.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Changes one thing in the save
+	edit := func(change func(saved map[string]interface{})) []byte {
+		var saved map[string]interface{}
+		json.Unmarshal(good, &saved)
+		change(saved)
+		data, _ := json.Marshal(saved)
+		return data
+	}
+
+	jsonTests := map[string][]byte{
+		"not json":     []byte("{"),
+		"newer":        edit(func(s map[string]interface{}) { s["version"] = SAVE_VERSION + 1 }),
+		"no version":   edit(func(s map[string]interface{}) { delete(s, "version") }),
+		"bad tile":     edit(func(s map[string]interface{}) { s["board"].(map[string]interface{})["tiles"].([]interface{})[0] = -3 }),
+		"short board":  edit(func(s map[string]interface{}) { s["board"].(map[string]interface{})["tiles"] = []int{0} }),
+		"bad shape":    edit(func(s map[string]interface{}) { s["active"].(map[string]interface{})["shape"] = 1000 }),
+		"bad rotation": edit(func(s map[string]interface{}) { s["active"].(map[string]interface{})["rotation"] = 4 }),
+		"off board":    edit(func(s map[string]interface{}) { s["active"].(map[string]interface{})["x"] = -50 }),
+		"in the way": edit(func(s map[string]interface{}) {
+			p := game.controller.tet.ListPositions()[0]
+			s["board"].(map[string]interface{})["tiles"].([]interface{})[p.y*BOARD_WIDTH+p.x] = C1
+		}),
+		"no preview":   edit(func(s map[string]interface{}) { s["preview"] = []int{} }),
+		"bad phase":    edit(func(s map[string]interface{}) { s["phase"] = 9 }),
+		"bad buffered": edit(func(s map[string]interface{}) { s["buffered"] = []int{-1} }),
+		"dealt a lot":  edit(func(s map[string]interface{}) { s["dealt"] = 1 << 40 }),
+		"bad gravity":  edit(func(s map[string]interface{}) { s["gravity"] = 1 }),
+		"played a lot": edit(func(s map[string]interface{}) { s["ticks"], s["dealt"] = 1<<40, 1<<41 }),
+		"long preview": edit(func(s map[string]interface{}) {
+			s["preview"] = append(s["preview"].([]interface{}), s["preview"].([]interface{})[0])
+		}),
+	}
+
+	newer := append([]byte(nil), binaryGood...)
+	newer[len(SAVE_MAGIC)+1]++
+	binaryTests := map[string][]byte{
+		"empty":      nil,
+		"not a save": []byte("hello there"),
+		"newer":      newer,
+		"cut short":  binaryGood[:len(binaryGood)/2],
+	}
+
+	for name, data := range jsonTests {
+		loaded := NewGame(1, 1)
+		before := loaded.Snap()
+		if err := loaded.UnmarshalJSON(data); !errors.Is(err, ErrInvalidSave) {
+			t.Errorf("json %v: expected ErrInvalidSave, found %v", name, err)
+		}
+		if !reflect.DeepEqual(before, loaded.Snap()) {
+			t.Errorf("json %v: expected the game to be left alone", name)
+		}
+	}
+
+	for name, data := range binaryTests {
+		loaded := NewGame(1, 1)
+		if err := loaded.UnmarshalBinary(data); !errors.Is(err, ErrInvalidSave) {
+			t.Errorf("binary %v: expected ErrInvalidSave, found %v", name, err)
+		}
+	}
+}
+
+func TestGameLoadDuringDelay(t *testing.T) {
+	opts := []GameOption{WithEntryDelay(3 * DEFAULT_FRAME)}
+	game := NewGame(0, 1, opts...)
+	game.Step([]Movement{MOVE_SLAM})
+	if game.phase != PHASE_ENTRY {
+		t.Fatalf("Expected to be waiting on the next tetromino, found %v", game.phase)
+	}
+	next := game.preview[0].GetShape()
+
+	data, err := game.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The delay starts over once the loaded game is stepped
+	loaded := NewGame(0, 1, opts...)
+	if err := loaded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+
+	stepFrames(loaded, 2)
+	if loaded.phase != PHASE_ENTRY {
+		t.Fatal("Tetromino came in before the delay was up")
+	}
+
+	loaded.Step(nil)
+	if loaded.phase != PHASE_ACTIVE || loaded.controller.tet.GetShape() != next {
+		t.Errorf("Expected the next tetromino to come in, found %v", loaded.phase)
+	}
+
+	// A game without the delay can't pick it up
+	loaded = NewGame(0, 1)
+	if err := loaded.UnmarshalBinary(data); !errors.Is(err, ErrInvalidSave) {
+		t.Errorf("Expected ErrInvalidSave, found %v", err)
+	}
+}
+
+func TestGameLoadStepped(t *testing.T) {
+	// Without a lock delay to start over, a stepped game that's saved
+	// and loaded plays on exactly like one that never stopped
+	opts := []GameOption{WithLockDelay(0, 0)}
+	game := NewGame(5, 1, opts...)
+	r := rand.New(rand.NewSource(1))
+	moves := []Movement{MOVE_LEFT, MOVE_RIGHT, MOVE_ROTATE_LEFT, MOVE_DOWN}
+	step := func(games ...*Game) {
+		move := []Movement{moves[r.Intn(len(moves))]}
+		for _, g := range games {
+			g.Step(move)
+		}
+	}
+
+	for i := 0; i < 100; i++ {
+		step(game)
+	}
+	data, err := game.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := NewGame(0, 1, opts...)
+	if err := loaded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Frame() != game.Frame() || loaded.steps.gravity != game.steps.gravity {
+		t.Fatalf("Expected frame %v and gravity %v, found %v and %v", game.Frame(), game.steps.gravity, loaded.Frame(), loaded.steps.gravity)
+	}
+
+	for i := 0; i < 200; i++ {
+		step(game, loaded)
+
+		want, _ := json.Marshal(game.Snap())
+		found, _ := json.Marshal(loaded.Snap())
+		if string(want) != string(found) {
+			t.Fatalf("Frame %v: expected %s, found %s", game.Frame(), want, found)
+		}
+	}
+}
+
+func TestGameLoadOtherOptions(t *testing.T) {
+	keepShapes(t)
+
+	game := NewGame(3, 1)
+	playRandom(game, rand.New(rand.NewSource(2)), 20)
+	data, err := game.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := NewGame(0, 1).UnmarshalBinary(data); err != nil {
+		t.Fatalf("Expected the game to load with the same options, found %v", err)
+	}
+
+	tests := map[string][]GameOption{
+		"rotation":   {WithRotationSystem(NESRotation{})},
+		"scorer":     {WithScorer(NewGuidelineScorer())},
+		"rules":      {WithRuleset(GuidelineRuleset())},
+		"preview":    {WithPreview(3)},
+		"lock delay": {WithLockDelay(time.Second, 3)},
+		"randomizer": {WithRandomizer(NewFourteenBagRandomizer)},
+		"frame":      {WithFrameDuration(time.Second / 30)},
+		"nes":        {WithNES()},
+	}
+	for name, opts := range tests {
+		if err := NewGame(0, 1, opts...).UnmarshalBinary(data); !errors.Is(err, ErrInvalidSave) {
+			t.Errorf("%v: expected ErrInvalidSave, found %v", name, err)
+		}
+	}
+
+	// Registering another shape, like loading a different definition
+	// file does, changes the shapes the save refers to
+	RegisterShape(NewTetGrid([]bool{true}), color.RGBA{})
+	if err := NewGame(0, 1).UnmarshalBinary(data); !errors.Is(err, ErrInvalidSave) {
+		t.Errorf("Expected the game not to load with other shapes, found %v", err)
+	}
+}
+
+func TestGameLoadOtherRandomizer(t *testing.T) {
+	// Randomizers that deal the same shapes as each other are still told
+	// apart, even if the preview can't tell them apart
+	sameAsBag := func(seed int64) Randomizer { return NewSevenBagRandomizer(seed) }
+
+	game := NewGame(3, 1)
+	data, err := game.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = NewGame(0, 1, WithRandomizer(sameAsBag)).UnmarshalBinary(data)
+	if !errors.Is(err, ErrInvalidSave) || !strings.Contains(err.Error(), "randomizer") {
+		t.Errorf("Expected the game not to load with another randomizer, found %v", err)
+	}
+	if err := NewGame(0, 1, WithRandomizer(Randomizers["7bag"])).UnmarshalBinary(data); err != nil {
+		t.Errorf("Expected the game to load with the same randomizer, found %v", err)
+	}
+}
+
+func TestGameLoadOtherKicks(t *testing.T) {
+	keepShapes(t)
+
+	def, err := LoadDefinition(strings.NewReader(testDefinition))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	game := NewGame(3, 1, WithRotationSystem(def.Rotation), WithRuleset(def.Rules))
+	data, err := game.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The same pieces, with a different kick for one of them
+	rs := def.Rotation.(DefinedRotation)
+	other := DefinedRotation{masks: rs.masks, kicks: make(map[Shape]kickTable)}
+	for s, kicks := range rs.kicks {
+		other.kicks[s] = kicks
+	}
+	kicks := other.kicks[def.Shapes[0]]
+	kicks[0][3] = []Position{{0, 0}, {1, 0}}
+	other.kicks[def.Shapes[0]] = kicks
+
+	err = NewGame(0, 1, WithRotationSystem(other), WithRuleset(def.Rules)).UnmarshalBinary(data)
+	if !errors.Is(err, ErrInvalidSave) {
+		t.Errorf("Expected the game not to load with other kicks, found %v", err)
+	}
+	if err := NewGame(0, 1, WithRotationSystem(def.Rotation), WithRuleset(def.Rules)).UnmarshalBinary(data); err != nil {
+		t.Errorf("Expected the game to load with the same kicks, found %v", err)
+	}
+}
+
+func TestGuidelineScorerSave(t *testing.T) {
+	s := NewGuidelineScorer()
+	s.Score(ScoreEvent{Locked: true, Lines: 4, Level: 1})
+	s.Score(ScoreEvent{Locked: true, Lines: 1, Level: 1})
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := NewGuidelineScorer()
+	if err := json.Unmarshal(data, loaded); err != nil {
+		t.Fatal(err)
+	}
+	if *loaded != *s {
+		t.Errorf("Expected %+v, found %+v", *s, *loaded)
+	}
+}
@@ -0,0 +1,153 @@
+package lib
+
+import (
+	"encoding/json"
+)
+
+// Everything a scorer gets to know about a single tick of the game
+type ScoreEvent struct {
+	// Whether a tetromino was locked in place this tick
+	Locked bool
+	// Number of lines cleared by the lock
+	Lines int
+	// The kind of T-spin the tetromino was locked with
+	TSpin TSpin
+	// Whether the lines cleared left the board empty
+	PerfectClear bool
+	// Rows fallen because of a soft drop or a slam
+	SoftDrop int
+	HardDrop int
+	// The level before any lines from this tick are counted
+	Level    int
+	Ticks    int
+	Gameover bool
+}
+
+// A Scorer decides how many points a tick is worth. Scorers are
+// allowed to remember what came before, so a new one should be made
+// for each game.
+type Scorer interface {
+	Score(event ScoreEvent) int
+}
+
+// Scorers by name, for picking one from a flag
+var Scorers = map[string]func() Scorer{
+	"guideline": func() Scorer { return NewGuidelineScorer() },
+	"classic":   func() Scorer { return ClassicScorer{} },
+}
+
+// The scoring the game originally shipped with. Every tick that
+// doesn't clear lines is worth the number of ticks so far times the
+// level, and there's a bonus at the end of the game. Lines themselves
+// aren't worth anything.
+type ClassicScorer struct{}
+
+func (ClassicScorer) Score(event ScoreEvent) int {
+	if event.Lines > 0 {
+		return 0
+	}
+
+	if !event.Gameover {
+		return event.Ticks * event.Level
+	}
+
+	// Game is over, hand out the end bonuses
+	score := event.Ticks + event.Level*10
+	if event.Level == MAX_LEVEL {
+		score += 1000
+	}
+	return score
+}
+
+// Points for clearing lines, indexed by the kind of T-spin and then
+// the number of lines. These get multiplied by the level.
+var lineScores = [...][5]int{
+	NO_TSPIN:   {0, 100, 300, 500, 800},
+	TSPIN_MINI: {100, 200, 400},
+	TSPIN:      {400, 800, 1200, 1600},
+}
+
+// Points for a perfect clear, on top of the lines themselves. A back
+// to back tetris perfect clear gets it's own value.
+var perfectClearScores = [...]int{0, 800, 1200, 1800, 2000}
+
+const B2B_PERFECT_CLEAR_SCORE = 3200
+const COMBO_SCORE = 50
+
+// Scoring as described by the tetris guideline. Line clears and
+// T-spins are worth more at higher levels, clearing lines with
+// consecutive tetrominos builds a combo, and a tetris or T-spin
+// straight after another one is worth half as much again.
+type GuidelineScorer struct {
+	// Number of consecutive locks that cleared lines, minus one. The
+	// first clear doesn't count as a combo
+	combo int
+	// Whether the last clear was a tetris or T-spin
+	backToBack bool
+}
+
+func NewGuidelineScorer() *GuidelineScorer {
+	return &GuidelineScorer{combo: -1}
+}
+
+// What a GuidelineScorer remembers, for saving games
+type guidelineScorerState struct {
+	Combo      int  `json:"combo"`
+	BackToBack bool `json:"backToBack"`
+}
+
+func (s *GuidelineScorer) MarshalJSON() ([]byte, error) {
+	return json.Marshal(guidelineScorerState{s.combo, s.backToBack})
+}
+
+func (s *GuidelineScorer) UnmarshalJSON(data []byte) error {
+	var state guidelineScorerState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+
+	s.combo, s.backToBack = state.Combo, state.BackToBack
+	return nil
+}
+
+func (s *GuidelineScorer) Score(event ScoreEvent) int {
+	// Drops are worth the same at any level
+	score := event.SoftDrop + 2*event.HardDrop
+
+	if !event.Locked {
+		return score
+	}
+
+	if event.Lines > 4 {
+		// Can't happen with tetrominos, but don't trust the caller
+		event.Lines = 4
+	}
+	points := lineScores[event.TSpin][event.Lines]
+
+	if event.Lines == 0 {
+		// Locking without clearing anything breaks a combo, but
+		// leaves a back to back alone
+		s.combo = -1
+		return score + points*event.Level
+	}
+
+	difficult := event.Lines == 4 || event.TSpin != NO_TSPIN
+	wasBackToBack := s.backToBack
+	if difficult && wasBackToBack {
+		points += points / 2
+	}
+	s.backToBack = difficult
+
+	if event.PerfectClear {
+		if event.Lines == 4 && wasBackToBack {
+			points += B2B_PERFECT_CLEAR_SCORE
+		} else {
+			points += perfectClearScores[event.Lines]
+		}
+	}
+
+	s.combo++
+	points += COMBO_SCORE * s.combo
+
+	return score + points*event.Level
+}
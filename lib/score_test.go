@@ -0,0 +1,72 @@
+package lib
+
+import (
+	"testing"
+)
+
+func TestClassicScorer(t *testing.T) {
+	scorer := ClassicScorer{}
+
+	if score := scorer.Score(ScoreEvent{Ticks: 10, Level: 3}); score != 30 {
+		t.Errorf("Expected ticks times level, found %v", score)
+	}
+
+	if score := scorer.Score(ScoreEvent{Locked: true, Lines: 4, Ticks: 10, Level: 3}); score != 0 {
+		t.Errorf("Expected clearing lines to score nothing, found %v", score)
+	}
+
+	if score := scorer.Score(ScoreEvent{Ticks: 10, Level: MAX_LEVEL, Gameover: true}); score != 10+MAX_LEVEL*10+1000 {
+		t.Errorf("Unexpected end bonus, found %v", score)
+	}
+}
+
+func TestGuidelineScorer(t *testing.T) {
+	// Each event is scored in order by the same scorer, so combos and
+	// back to backs carry over
+	events := []struct {
+		name     string
+		event    ScoreEvent
+		expected int
+	}{
+		{"soft drop", ScoreEvent{SoftDrop: 1, Level: 2}, 1},
+		{"lock", ScoreEvent{Locked: true, HardDrop: 5, Level: 2}, 10},
+		{"single", ScoreEvent{Locked: true, Lines: 1, Level: 2}, 200},
+		{"combo double", ScoreEvent{Locked: true, Lines: 2, Level: 2}, (300 + 50) * 2},
+		{"combo tetris", ScoreEvent{Locked: true, Lines: 4, Level: 2}, (800 + 100) * 2},
+		{"b2b tetris", ScoreEvent{Locked: true, Lines: 4, Level: 2}, (1200 + 150) * 2},
+		{"combo break", ScoreEvent{Locked: true, Level: 2}, 0},
+		{"t-spin", ScoreEvent{Locked: true, TSpin: TSPIN, Level: 2}, 800},
+		{"b2b t-spin double", ScoreEvent{Locked: true, Lines: 2, TSpin: TSPIN, Level: 2}, 1800 * 2},
+		{"b2b break", ScoreEvent{Locked: true, Lines: 1, Level: 2}, (100 + 50) * 2},
+		{"mini single", ScoreEvent{Locked: true, Lines: 1, TSpin: TSPIN_MINI, Level: 2}, (200 + 100) * 2},
+		{"b2b tetris perfect clear", ScoreEvent{Locked: true, Lines: 4, PerfectClear: true, Level: 1}, 1200 + 3200 + 150},
+		{"double perfect clear", ScoreEvent{Locked: true, Lines: 2, PerfectClear: true, Level: 1}, 300 + 1200 + 200},
+	}
+
+	scorer := NewGuidelineScorer()
+	for _, e := range events {
+		if score := scorer.Score(e.event); score != e.expected {
+			t.Errorf("%v: expected %v, found %v", e.name, e.expected, score)
+		}
+	}
+}
+
+func TestGameScoresLines(t *testing.T) {
+	game := NewGame(0, 1, WithScorer(NewGuidelineScorer()))
+
+	// Leave a gap for the active tetromino to fill
+	board := game.controller.board
+	for x := 0; x < BOARD_WIDTH; x++ {
+		board.SetTile(C1, x, 0)
+	}
+	for _, p := range game.controller.dropped().ListPositions() {
+		if p.y == 0 {
+			board.SetTile(EMPTY, p.x, p.y)
+		}
+	}
+
+	game.Tick(MOVE_SLAM)
+	if game.lines != 1 || game.score < 100 {
+		t.Errorf("Expected a scored line clear, found %v lines and a score of %v", game.lines, game.score)
+	}
+}
@@ -0,0 +1,170 @@
+package lib
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// The version of the JSON form of a snapshot. It goes up whenever a
+// field is removed or changes meaning, adding fields doesn't change it.
+const SNAPSHOT_VERSION = 1
+
+// Snapshots are encoded as JSON for tools outside of the game, like
+// overlays and replays, like so:
+//
+//	{
+//	    "version": 1,
+//	    "score": 1200, "level": 2, "ticks": 340, "frame": 0,
+//	    "gravity": 910,
+//	    "phase": "active",
+//	    "clearing": [],
+//	    "board": {
+//	        "width": 10, "height": 40, "visible": 20,
+//	        "rows": [[1, 1, 0, 0, 2, 2, 2, 2, 0, 0], ...]
+//	    },
+//	    "active": {
+//	        "shape": 4, "rotation": 0,
+//	        "mask": ["...", "###", ".#."],
+//	        "position": [3, 21],
+//	        "cells": [[3, 20], [4, 20], [5, 20], [4, 19]]
+//	    },
+//	    "ghost": [[3, 1], [4, 1], [5, 1], [4, 0]],
+//	    "next": [{"shape": 6, "rotation": 0, "mask": [".#..", ".#..", ".#..", ".#.."]}],
+//	    "held": null
+//	}
+//
+// Coordinates are [x, y] with 0,0 at the bottom left of the board, the
+// same as everywhere else in the game. The board's rows start from the
+// bottom, so rows[y][x] is the tile at x,y, with 0 for EMPTY and a
+// shape's tiles being one more than the shape. Every row is there,
+// including the hidden ones above the visible rows.
+//
+// Pieces have their shape, and their rotation as the index into the
+// rotation system's masks, which counts left rotations from the first
+// mask. The mask is drawn top row first with a '#' for each tile, like
+// in a definition file. The piece in play also has the position of the
+// top left of it's mask, and the cells it covers on the board. Outside
+// of the active phase nothing is in play, so active and ghost are null.
+//
+// Gravity is how many milliseconds it takes the piece in play to fall
+// a row, and the phase is one of "active", "line clear" or "entry".
+// Snapshots can only be encoded, they aren't meant to be read back in.
+type snapshotJSON struct {
+	Version  int             `json:"version"`
+	Score    int             `json:"score"`
+	Level    int             `json:"level"`
+	Ticks    int             `json:"ticks"`
+	Frame    int             `json:"frame"`
+	Gravity  float64         `json:"gravity"`
+	Phase    string          `json:"phase"`
+	Clearing []int           `json:"clearing"`
+	Board    snapshotBoard   `json:"board"`
+	Active   *snapshotActive `json:"active"`
+	Ghost    [][2]int        `json:"ghost"`
+	Next     []snapshotPiece `json:"next"`
+	Held     *snapshotPiece  `json:"held"`
+}
+
+type snapshotBoard struct {
+	Width   int           `json:"width"`
+	Height  int           `json:"height"`
+	Visible int           `json:"visible"`
+	Rows    [][]TileColor `json:"rows"`
+}
+
+type snapshotPiece struct {
+	Shape    Shape    `json:"shape"`
+	Rotation int      `json:"rotation"`
+	Mask     []string `json:"mask"`
+}
+
+type snapshotActive struct {
+	snapshotPiece
+	Position [2]int   `json:"position"`
+	Cells    [][2]int `json:"cells"`
+}
+
+func snapshotPieceOf(tet *Tetromino) snapshotPiece {
+	piece := snapshotPiece{Shape: tet.shape, Rotation: tet.rotationIdx, Mask: []string{}}
+	if tet.mask == nil {
+		return piece
+	}
+
+	mask := *tet.mask
+	for dy := 0; dy < tet.size; dy++ {
+		row := &strings.Builder{}
+		for dx := 0; dx < tet.size; dx++ {
+			if mask[dy*tet.size+dx] {
+				row.WriteByte('#')
+			} else {
+				row.WriteByte('.')
+			}
+		}
+		piece.Mask = append(piece.Mask, row.String())
+	}
+
+	return piece
+}
+
+func snapshotCells(ps []Position) [][2]int {
+	cells := make([][2]int, len(ps))
+	for i, p := range ps {
+		cells[i] = [2]int{p.x, p.y}
+	}
+
+	return cells
+}
+
+// Encodes the snapshot in it's JSON form, see snapshotJSON for what's
+// in it
+func (snap GameSnapshot) MarshalJSON() ([]byte, error) {
+	board := &snap.Board
+	out := snapshotJSON{
+		Version:  SNAPSHOT_VERSION,
+		Score:    snap.Score,
+		Level:    snap.Level,
+		Ticks:    snap.Ticks,
+		Frame:    snap.Frame,
+		Gravity:  float64(snap.Gravity) / float64(time.Millisecond),
+		Phase:    snap.Phase.String(),
+		Clearing: append([]int{}, snap.Clearing...),
+		Board: snapshotBoard{
+			Width:   board.Width(),
+			Height:  board.Height(),
+			Visible: board.Visible(),
+			Rows:    make([][]TileColor, board.Height()),
+		},
+		Next: make([]snapshotPiece, len(snap.Preview)),
+	}
+
+	for y := range out.Board.Rows {
+		row := make([]TileColor, board.Width())
+		for x := range row {
+			row[x] = board.GetTile(x, y)
+		}
+		out.Board.Rows[y] = row
+	}
+
+	if snap.Phase == PHASE_ACTIVE && snap.CurrentTet.mask != nil {
+		tet := ActiveTetromino{&snap.CurrentTet, snap.Position}
+		out.Active = &snapshotActive{
+			snapshotPiece: snapshotPieceOf(&snap.CurrentTet),
+			Position:      [2]int{snap.Position.x, snap.Position.y},
+			Cells:         snapshotCells(tet.ListPositions()),
+		}
+	}
+	if snap.Ghost != nil {
+		out.Ghost = snapshotCells(snap.Ghost)
+	}
+
+	for i := range snap.Preview {
+		out.Next[i] = snapshotPieceOf(&snap.Preview[i])
+	}
+	if snap.HeldTet != nil {
+		held := snapshotPieceOf(snap.HeldTet)
+		out.Held = &held
+	}
+
+	return json.Marshal(out)
+}
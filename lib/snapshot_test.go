@@ -0,0 +1,153 @@
+package lib
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// The JSON form of a snapshot, as an outside tool would read it
+type testSnapshotJSON struct {
+	Version  int
+	Score    int
+	Level    int
+	Ticks    int
+	Gravity  float64
+	Phase    string
+	Clearing []int
+	Board    struct {
+		Width, Height, Visible int
+		Rows                   [][]TileColor
+	}
+	Active *struct {
+		Shape    Shape
+		Rotation int
+		Mask     []string
+		Position [2]int
+		Cells    [][2]int
+	}
+	Ghost [][2]int
+	Next  []struct {
+		Shape    Shape
+		Rotation int
+		Mask     []string
+	}
+	Held *struct {
+		Shape Shape
+	}
+}
+
+func decodeSnapshot(t *testing.T, snap GameSnapshot) testSnapshotJSON {
+	t.Helper()
+
+	data, err := json.Marshal(snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded testSnapshotJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
+
+func TestGameSnapshotJSON(t *testing.T) {
+	game := NewGame(0, 1, WithBoardSize(8, 16), WithPreview(3))
+	game.Tick(MOVE_SLAM)
+	game.Tick(MOVE_HOLD)
+	game.Tick(MOVE_ROTATE_RIGHT)
+
+	snap := game.Snap()
+	decoded := decodeSnapshot(t, snap)
+
+	if decoded.Version != SNAPSHOT_VERSION {
+		t.Errorf("Expected version %v, found %v", SNAPSHOT_VERSION, decoded.Version)
+	}
+	if decoded.Score != snap.Score || decoded.Level != snap.Level || decoded.Ticks != snap.Ticks || decoded.Phase != "active" {
+		t.Errorf("Expected the stats to match the snapshot, found %+v", decoded)
+	}
+	if decoded.Gravity <= 0 {
+		t.Errorf("Expected the gravity in milliseconds, found %v", decoded.Gravity)
+	}
+
+	// Rows go from the bottom up, and have every tile in them
+	board := decoded.Board
+	if board.Width != 8 || board.Visible != 16 || board.Height != snap.Board.Height() || len(board.Rows) != board.Height {
+		t.Fatalf("Expected an 8x16 board with all of it's rows, found %+v", board)
+	}
+	var tiles int
+	for y, row := range board.Rows {
+		for x, tile := range row {
+			if tile != snap.Board.GetTile(x, y) {
+				t.Fatalf("Expected %v at %v,%v, found %v", snap.Board.GetTile(x, y), x, y, tile)
+			}
+			if tile != EMPTY {
+				tiles++
+			}
+		}
+	}
+	if tiles != 4 {
+		t.Errorf("Expected the slammed tetromino on the board, found %v tiles", tiles)
+	}
+
+	active := decoded.Active
+	if active == nil {
+		t.Fatal("Expected the tetromino in play")
+	}
+	if active.Shape != snap.CurrentTet.shape || active.Rotation != snap.CurrentTet.rotationIdx {
+		t.Errorf("Expected shape %v turned %v, found %v turned %v", snap.CurrentTet.shape, snap.CurrentTet.rotationIdx, active.Shape, active.Rotation)
+	}
+	if active.Position != [2]int{snap.Position.x, snap.Position.y} {
+		t.Errorf("Expected the tetromino at %v, found %v", snap.Position, active.Position)
+	}
+
+	// The cells are where the mask puts the tiles on the board
+	cells := [][2]int{}
+	for dy, row := range active.Mask {
+		for dx, c := range row {
+			if c == '#' {
+				cells = append(cells, [2]int{active.Position[0] + dx, active.Position[1] - dy})
+			}
+		}
+	}
+	if !reflect.DeepEqual(cells, active.Cells) {
+		t.Errorf("Expected the cells %v from the mask, found %v", cells, active.Cells)
+	}
+	if len(decoded.Ghost) != len(snap.Ghost) {
+		t.Errorf("Expected the ghost %v, found %v", snap.Ghost, decoded.Ghost)
+	}
+
+	if len(decoded.Next) != 3 {
+		t.Fatalf("Expected 3 upcoming tetrominos, found %v", len(decoded.Next))
+	}
+	for i, next := range decoded.Next {
+		if next.Shape != snap.Preview[i].shape || len(next.Mask) != snap.Preview[i].size {
+			t.Errorf("Preview %v doesn't match, found %+v", i, next)
+		}
+	}
+	if decoded.Held == nil || decoded.Held.Shape != snap.HeldTet.shape {
+		t.Errorf("Expected the held tetromino, found %+v", decoded.Held)
+	}
+}
+
+func TestGameSnapshotJSONDelay(t *testing.T) {
+	game := NewGame(0, 1, WithEntryDelay(3*DEFAULT_FRAME))
+	game.Step([]Movement{MOVE_SLAM})
+
+	decoded := decodeSnapshot(t, game.Snap())
+	if decoded.Phase != "entry" {
+		t.Errorf("Expected the entry phase, found %q", decoded.Phase)
+	}
+	if decoded.Active != nil || decoded.Ghost != nil {
+		t.Errorf("Expected nothing in play, found %+v and %v", decoded.Active, decoded.Ghost)
+	}
+	if decoded.Held != nil || decoded.Clearing == nil {
+		t.Errorf("Expected no held tetromino and no rows clearing, found %+v and %v", decoded.Held, decoded.Clearing)
+	}
+
+	// Even an empty snapshot can be encoded
+	if _, err := json.Marshal(GameSnapshot{}); err != nil {
+		t.Error(err)
+	}
+}
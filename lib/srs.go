@@ -0,0 +1,139 @@
+package lib
+
+// The Super Rotation System (SRS) names each of the four orientations
+// a tetromino can be in. SRS_0 is the orientation a piece spawns in,
+// and each following state is one clockwise rotation from the last.
+type RotationState int
+
+const (
+	SRS_0 RotationState = iota
+	SRS_R
+	SRS_2
+	SRS_L
+)
+
+// The grids in tetromino.go aren't all drawn in the SRS spawn
+// orientation, so this records which state each of them starts in. We
+// need it to find where a tetromino sits in the kick tables.
+var srsBaseStates = [...]RotationState{
+	TET_SQUARE: SRS_0,
+	TET_S:      SRS_2,
+	TET_Z:      SRS_2,
+	TET_L:      SRS_R,
+	TET_T:      SRS_2,
+	TET_J:      SRS_L,
+	TET_LINE:   SRS_L,
+}
+
+// Returns the state a shape's grid is drawn in. Registered shapes are
+// taken to be drawn in their spawn orientation.
+func srsBaseState(s Shape) RotationState {
+	if int(s) < len(srsBaseStates) {
+		return srsBaseStates[s]
+	}
+
+	return SRS_0
+}
+
+// Returns the SRS state that a tetromino is currently in. Only
+// meaningful for tetrominos using the default masks.
+func srsState(tet *Tetromino) RotationState {
+	// The rotation index counts left (counter clockwise) rotations,
+	// but the SRS states are ordered clockwise, so we subtract
+	state := int(srsBaseState(tet.shape)) - tet.rotationIdx
+	return RotationState((state%4 + 4) % 4)
+}
+
+// Kick offsets for the J, L, S, T and Z tetrominos. Indexed by the
+// state we're rotating from, and then by the state we're rotating
+// to. Only neighbouring states have entries. Positive y moves up the
+// board, just like it does everywhere else.
+var jlstzKicks = [4][4][]Position{
+	SRS_0: {
+		SRS_R: {{0, 0}, {-1, 0}, {-1, 1}, {0, -2}, {-1, -2}},
+		SRS_L: {{0, 0}, {1, 0}, {1, 1}, {0, -2}, {1, -2}},
+	},
+	SRS_R: {
+		SRS_0: {{0, 0}, {1, 0}, {1, -1}, {0, 2}, {1, 2}},
+		SRS_2: {{0, 0}, {1, 0}, {1, -1}, {0, 2}, {1, 2}},
+	},
+	SRS_2: {
+		SRS_R: {{0, 0}, {-1, 0}, {-1, 1}, {0, -2}, {-1, -2}},
+		SRS_L: {{0, 0}, {1, 0}, {1, 1}, {0, -2}, {1, -2}},
+	},
+	SRS_L: {
+		SRS_2: {{0, 0}, {-1, 0}, {-1, -1}, {0, 2}, {-1, 2}},
+		SRS_0: {{0, 0}, {-1, 0}, {-1, -1}, {0, 2}, {-1, 2}},
+	},
+}
+
+// The line piece has it's own table, since it's wider than the others
+var lineKicks = [4][4][]Position{
+	SRS_0: {
+		SRS_R: {{0, 0}, {-2, 0}, {1, 0}, {-2, -1}, {1, 2}},
+		SRS_L: {{0, 0}, {-1, 0}, {2, 0}, {-1, 2}, {2, -1}},
+	},
+	SRS_R: {
+		SRS_0: {{0, 0}, {2, 0}, {-1, 0}, {2, 1}, {-1, -2}},
+		SRS_2: {{0, 0}, {-1, 0}, {2, 0}, {-1, 2}, {2, -1}},
+	},
+	SRS_2: {
+		SRS_R: {{0, 0}, {1, 0}, {-2, 0}, {1, -2}, {-2, 1}},
+		SRS_L: {{0, 0}, {2, 0}, {-1, 0}, {2, 1}, {-1, -2}},
+	},
+	SRS_L: {
+		SRS_2: {{0, 0}, {-2, 0}, {1, 0}, {-2, -1}, {1, 2}},
+		SRS_0: {{0, 0}, {1, 0}, {-2, 0}, {1, -2}, {-2, 1}},
+	},
+}
+
+// The square never needs to move, since rotating it changes nothing
+var squareKicks = []Position{{0, 0}}
+
+// Returns the offsets that should be tried, in order, when rotating
+// the given shape between two states. The first offset that results
+// in a legal position is the one that gets used.
+func srsKicks(s Shape, from, to RotationState) []Position {
+	switch s {
+	case TET_SQUARE:
+		return squareKicks
+	case TET_LINE:
+		return lineKicks[from][to]
+	default:
+		return jlstzKicks[from][to]
+	}
+}
+
+// The Super Rotation System used by modern guideline games. Pieces
+// spawn flat side up, and when a rotation doesn't fit in place each of
+// the kick offsets is tried in order until one of them does.
+type SRSRotation struct{}
+
+func (SRSRotation) Spawn(tet *Tetromino) {
+	// srsState subtracts the rotation index from the base state, so
+	// this is the index that puts the tetromino in SRS_0
+	tet.setMasks(rotations[tet.shape], int(srsBaseState(tet.shape)))
+}
+
+func (SRSRotation) Rotate(tet ActiveTetromino, isLeft bool, board *Board) (ActiveTetromino, bool) {
+	rotationFunc, rotationInverse := rotationFuncs(tet.Tetromino, isLeft)
+
+	from := srsState(tet.Tetromino)
+	rotationFunc()
+	to := srsState(tet.Tetromino)
+
+	var projectedTet ActiveTetromino
+	for _, kick := range srsKicks(tet.shape, from, to) {
+		projectedTet = tet
+		projectedTet.x += kick.x
+		projectedTet.y += kick.y
+
+		if projectedTet.fits(board) {
+			return projectedTet, true
+		}
+	}
+
+	// Nothing fit, undo the rotation. The operation is idempotent
+	rotationInverse()
+	return tet, false
+}
@@ -0,0 +1,219 @@
+package lib
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Creates a tetromino that's been rotated into the given SRS state
+func tetInState(s Shape, state RotationState) *Tetromino {
+	tet := NewTet(s)
+	for srsState(tet) != state {
+		tet.RotLeft()
+	}
+
+	return tet
+}
+
+func TestSRSSpawnStates(t *testing.T) {
+	// These are the spawn orientations from the guideline, which is
+	// what SRS_0 should always look like
+	expected := map[Shape][]bool{
+		TET_SQUARE: {
+			true, true,
+			true, true,
+		},
+		TET_S: {
+			false, true, true,
+			true, true, false,
+			false, false, false,
+		},
+		TET_Z: {
+			true, true, false,
+			false, true, true,
+			false, false, false,
+		},
+		TET_L: {
+			false, false, true,
+			true, true, true,
+			false, false, false,
+		},
+		TET_T: {
+			false, true, false,
+			true, true, true,
+			false, false, false,
+		},
+		TET_J: {
+			true, false, false,
+			true, true, true,
+			false, false, false,
+		},
+		TET_LINE: {
+			false, false, false, false,
+			true, true, true, true,
+			false, false, false, false,
+			false, false, false, false,
+		},
+	}
+
+	for _, s := range shapes {
+		if mask := tetInState(s, SRS_0).GetMask(); !reflect.DeepEqual(mask, expected[s]) {
+			t.Errorf("Shape %v has the wrong spawn mask: %v", s, mask)
+		}
+	}
+}
+
+func TestSRSStateRotation(t *testing.T) {
+	for _, s := range shapes {
+		tet := tetInState(s, SRS_0)
+
+		tet.RotRight()
+		if state := srsState(tet); state != SRS_R {
+			t.Errorf("Shape %v expected state %v after a right rotation, found %v", s, SRS_R, state)
+		}
+
+		tet.RotLeft()
+		tet.RotLeft()
+		if state := srsState(tet); state != SRS_L {
+			t.Errorf("Shape %v expected state %v after a left rotation, found %v", s, SRS_L, state)
+		}
+	}
+}
+
+// Rotating from A to B and then from B to A should always put a
+// tetromino back where it started, so the tables must mirror each
+// other
+func TestSRSKickSymmetry(t *testing.T) {
+	for _, s := range shapes {
+		for from := SRS_0; from <= SRS_L; from++ {
+			to := (from + 1) % 4
+			there := srsKicks(s, from, to)
+			back := srsKicks(s, to, from)
+
+			if len(there) != len(back) {
+				t.Fatalf("Shape %v has mismatched kick tables between %v and %v", s, from, to)
+			}
+
+			for i := range there {
+				if there[i].x != -back[i].x || there[i].y != -back[i].y {
+					t.Errorf("Shape %v kick %v from %v to %v is %v, but the reverse is %v",
+						s, i, from, to, there[i], back[i])
+				}
+			}
+		}
+	}
+}
+
+// Sets up a board for every kick in every table, where all of the
+// kicks before it are blocked, and checks the tetromino lands on
+// exactly the offset we expect
+func TestSRSKicks(t *testing.T) {
+	const X, Y = 3, 10
+
+	contains := func(ps []Position, p Position) bool {
+		for _, c := range ps {
+			if c == p {
+				return true
+			}
+		}
+		return false
+	}
+
+	kickedShapes := []Shape{TET_S, TET_Z, TET_L, TET_T, TET_J, TET_LINE}
+
+	for _, s := range kickedShapes {
+		for from := SRS_0; from <= SRS_L; from++ {
+			for _, isLeft := range []bool{true, false} {
+				to := (from + 1) % 4
+				if isLeft {
+					to = (from + 3) % 4
+				}
+
+				kicks := srsKicks(s, from, to)
+
+				current := NewActiveTet(tetInState(s, from))
+				current.x, current.y = X, Y
+
+				rotated := NewActiveTet(tetInState(s, to))
+				targets := make([][]Position, len(kicks))
+				for i, kick := range kicks {
+					rotated.x, rotated.y = X+kick.x, Y+kick.y
+					targets[i] = rotated.ListPositions()
+				}
+
+				for i, kick := range kicks {
+					board := &Board{}
+
+					// Block every earlier kick with a tile that neither
+					// the current tetromino nor the expected kick use
+					reserved := append(current.ListPositions(), targets[i]...)
+					reachable := true
+					for j := 0; j < i; j++ {
+						var blocked bool
+						for _, p := range targets[j] {
+							if !contains(reserved, p) {
+								board.SetTile(C1, p.x, p.y)
+								blocked = true
+								break
+							}
+						}
+
+						reachable = reachable && blocked
+					}
+
+					// Some kicks can never be reached, because the only
+					// way to block an earlier kick is to fill a tile
+					// the later kick needs
+					if !reachable {
+						t.Logf("Kick %v for shape %v from %v to %v is unreachable", i, s, from, to)
+						continue
+					}
+
+					ctl := &BoardController{board: board, tet: NewActiveTet(tetInState(s, from)), rotation: SRSRotation{}, rules: ClassicRuleset()}
+					ctl.tet.x, ctl.tet.y = X, Y
+					ctl.rotate(isLeft)
+
+					expPos := Position{X + kick.x, Y + kick.y}
+					if ctl.tet.Position != expPos || srsState(ctl.tet.Tetromino) != to {
+						t.Errorf("Shape %v kick %v from %v to %v: expected %v in state %v, found %v in state %v",
+							s, i, from, to, expPos, to, ctl.tet.Position, srsState(ctl.tet.Tetromino))
+					}
+				}
+
+				// Finally block every kick, in which case the rotation
+				// shouldn't happen at all
+				board := &Board{}
+				for _, ps := range targets {
+					for _, p := range ps {
+						if !contains(current.ListPositions(), p) {
+							board.SetTile(C1, p.x, p.y)
+						}
+					}
+				}
+
+				ctl := &BoardController{board: board, tet: NewActiveTet(tetInState(s, from)), rotation: SRSRotation{}, rules: ClassicRuleset()}
+				ctl.tet.x, ctl.tet.y = X, Y
+				ctl.rotate(isLeft)
+
+				if ctl.tet.Position != current.Position || srsState(ctl.tet.Tetromino) != from {
+					t.Errorf("Shape %v rotated from %v to %v even though every kick was blocked", s, from, to)
+				}
+			}
+		}
+	}
+}
+
+func TestSRSSquareDoesNotKick(t *testing.T) {
+	board := &Board{}
+	ctl := &BoardController{board: board, tet: NewActiveTet(NewTet(TET_SQUARE)), rotation: SRSRotation{}, rules: ClassicRuleset()}
+	ctl.tet.x, ctl.tet.y = 0, 1
+
+	initPositions := ctl.tet.ListPositions()
+	ctl.RotLeft()
+	ctl.RotRight()
+	ctl.RotRight()
+
+	if !reflect.DeepEqual(initPositions, ctl.tet.ListPositions()) {
+		t.Errorf("Square moved when rotated. Expected %v, found %v", initPositions, ctl.tet.ListPositions())
+	}
+}
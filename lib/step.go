@@ -0,0 +1,173 @@
+package lib
+
+import (
+	"time"
+)
+
+// How long a frame lasts by default when the game is stepped, which is
+// 60 frames a second
+const DEFAULT_FRAME = time.Second / 60
+
+// Sets how long each frame lasts when the game is moved forward with
+// Step. Gravity and the lock delay are worked out in frames of this
+// length. Frames that aren't longer than zero fall back to
+// DEFAULT_FRAME.
+func WithFrameDuration(frame time.Duration) GameOption {
+	if frame <= 0 {
+		frame = DEFAULT_FRAME
+	}
+
+	return func(game *Game) {
+		game.frame = frame
+	}
+}
+
+// Everything Step keeps track of between frames
+type stepState struct {
+	// Number of frames stepped so far
+	frame int
+	// Frames since the tetromino last fell
+	gravity int
+	// Whether the timers below have been set up, which happens on the
+	// first step after the game is made or loaded
+	started bool
+	// Count down the delays, or nil before the first step and when
+	// there's no delay
+	lock  *frameTimer
+	clear *frameTimer
+	entry *frameTimer
+}
+
+// Counts down a delay a frame at a time. Like a ResetTimer, it
+// starts over each time it runs out, until it's stopped
+type frameTimer struct {
+	frames    int
+	remaining int
+	running   bool
+}
+
+func (t *frameTimer) Reset() {
+	t.remaining = t.frames
+	t.running = true
+}
+
+func (t *frameTimer) Stop() {
+	t.running = false
+}
+
+// Counts down a single frame. Returns true if the timer ran out
+func (t *frameTimer) step() bool {
+	if !t.running {
+		return false
+	}
+
+	t.remaining--
+	if t.remaining > 0 {
+		return false
+	}
+
+	t.remaining = t.frames
+	return true
+}
+
+// Returns the number of whole frames closest to a duration. Never less
+// than one, since nothing can happen faster than once a frame
+func (game *Game) toFrames(d time.Duration) int {
+	frames := int((d + game.frame/2) / game.frame)
+	if frames < 1 {
+		return 1
+	}
+	return frames
+}
+
+// Moves the game forward by a single frame. The delays and gravity are
+// counted down first, so a tetromino locks exactly the lock delay
+// after it landed, and falls exactly a gravity after it last fell.
+// Then the moves are applied in order, or held onto if there's no
+// tetromino in play. Nothing here depends on the time, so the same
+// moves on the same frames always play out the same way. A game should
+// either be stepped or played, not both.
+func (game *Game) Step(moves []Movement) {
+	if game.controller.isGameover {
+		return
+	}
+
+	state := &game.steps
+	if !state.started {
+		state.lock = game.newFrameTimer(game.lockDelay, &game.lockTimer)
+		state.clear = game.newFrameTimer(game.lineClearDelay, &game.clearTimer)
+		state.entry = game.newFrameTimer(game.entryDelay, &game.entryTimer)
+		game.resumeDelay()
+		state.started = true
+	}
+	state.frame++
+
+	// The entry delay goes first, so when the line clear delay ends and
+	// starts it, it isn't counted until the next frame
+	if state.entry != nil && state.entry.step() && game.phase == PHASE_ENTRY {
+		game.finishDelay()
+	}
+	if state.clear != nil && state.clear.step() && game.phase == PHASE_LINE_CLEAR {
+		game.finishDelay()
+	}
+
+	if state.lock != nil && state.lock.step() {
+		// Forcing down a tetromino that's on the ground locks it
+		if !game.controller.CanMoveDown() {
+			game.Tick(MOVE_FORCE_DOWN)
+		}
+	}
+
+	// Nothing falls between tetrominos, and gravity starts over when
+	// the next one comes in
+	if game.phase != PHASE_ACTIVE {
+		state.gravity = 0
+	} else {
+		state.gravity++
+	}
+
+	if state.gravity >= game.toFrames(game.gravity(game.Level())) {
+		state.gravity = 0
+
+		// Gravity has nothing to do once the tetromino has landed,
+		// unless there's no lock delay to lock it
+		if game.controller.CanMoveDown() || state.lock == nil {
+			game.Tick(MOVE_FORCE_DOWN)
+		}
+	}
+
+	for _, move := range moves {
+		if game.controller.isGameover {
+			return
+		}
+
+		// Soft drops and slams start gravity over, just like in Play
+		if game.controller.CanMoveDown() && move == MOVE_DOWN || move == MOVE_SLAM {
+			state.gravity = 0
+		}
+
+		game.Tick(move)
+	}
+}
+
+// Creates a stopped frameTimer for a delay and hands it to the game.
+// Returns nil if there's no delay
+func (game *Game) newFrameTimer(d time.Duration, t *delayTimer) *frameTimer {
+	if d <= 0 {
+		return nil
+	}
+
+	timer := &frameTimer{frames: game.toFrames(d)}
+	*t = timer
+	return timer
+}
+
+// Returns the number of frames the game has been stepped
+func (game *Game) Frame() int {
+	return game.steps.frame
+}
+
+// Returns true once the game is over
+func (game *Game) IsGameover() bool {
+	return game.controller.isGameover
+}
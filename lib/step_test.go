@@ -0,0 +1,163 @@
+package lib
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Steps the game some number of frames without any moves
+func stepFrames(game *Game, frames int) {
+	for i := 0; i < frames; i++ {
+		game.Step(nil)
+	}
+}
+
+func TestStepGravity(t *testing.T) {
+	for _, level := range []int{1, 10, 20} {
+		game := NewGame(0, level)
+		frames := game.toFrames(linearGravity(level))
+
+		for row := 0; row < 3; row++ {
+			y := game.controller.tet.y
+
+			stepFrames(game, frames-1)
+			if game.controller.tet.y != y {
+				t.Fatalf("Level %v: tetromino fell before %v frames", level, frames)
+			}
+
+			game.Step(nil)
+			if game.controller.tet.y != y-1 {
+				t.Fatalf("Level %v: tetromino didn't fall after %v frames", level, frames)
+			}
+		}
+	}
+}
+
+func TestStepNESGravity(t *testing.T) {
+	// NES games step at the NES frame rate, so the table is exact
+	game := NewGame(0, 0, WithNES())
+	y := game.controller.tet.y
+
+	stepFrames(game, 47)
+	if game.controller.tet.y != y {
+		t.Fatal("Tetromino fell before 48 frames")
+	}
+
+	game.Step(nil)
+	if game.controller.tet.y != y-1 {
+		t.Error("Tetromino didn't fall after 48 frames")
+	}
+
+	if game.Frame() != 48 {
+		t.Errorf("Expected 48 frames to be counted, found %v", game.Frame())
+	}
+}
+
+func TestStepSoftDropResetsGravity(t *testing.T) {
+	game := NewGame(0, 1)
+	frames := game.toFrames(linearGravity(1))
+
+	stepFrames(game, frames-1)
+	game.Step([]Movement{MOVE_DOWN})
+	y := game.controller.tet.y
+
+	// Gravity starts over from the soft drop
+	stepFrames(game, frames-1)
+	if game.controller.tet.y != y {
+		t.Errorf("Gravity wasn't reset by the soft drop")
+	}
+}
+
+func TestStepLockDelay(t *testing.T) {
+	game := NewGame(0, 1, WithLockDelay(500*time.Millisecond, 2))
+	frames := game.toFrames(500 * time.Millisecond)
+
+	for game.controller.CanMoveDown() {
+		game.Step([]Movement{MOVE_DOWN})
+	}
+	tet := game.controller.tet.Tetromino
+
+	// Moving restarts the delay, up to the limit. The delay is counted
+	// before moves, so they have to come a frame before it's due
+	for i := 0; i < 2; i++ {
+		stepFrames(game, frames-2)
+		game.Step([]Movement{[]Movement{MOVE_LEFT, MOVE_RIGHT}[i]})
+	}
+
+	// The resets are used up, so this move doesn't help
+	stepFrames(game, frames-2)
+	game.Step([]Movement{MOVE_LEFT})
+	if game.controller.tet.Tetromino != tet {
+		t.Fatal("Tetromino locked before the delay was up")
+	}
+
+	game.Step(nil)
+	if game.controller.tet.Tetromino == tet {
+		t.Errorf("Tetromino didn't lock %v frames after it's last reset", frames)
+	}
+}
+
+func TestStepNoLockDelay(t *testing.T) {
+	// Without a lock delay, gravity locks the tetromino
+	game := NewGame(0, 1, WithLockDelay(0, 0))
+	frames := game.toFrames(linearGravity(1))
+
+	for game.controller.CanMoveDown() {
+		game.Step([]Movement{MOVE_DOWN})
+	}
+	tet := game.controller.tet.Tetromino
+
+	stepFrames(game, frames)
+	if game.controller.tet.Tetromino == tet {
+		t.Error("Gravity didn't lock the tetromino")
+	}
+}
+
+func TestStepDeterministic(t *testing.T) {
+	moveOptions := []Movement{
+		MOVE_DOWN, MOVE_LEFT, MOVE_RIGHT, MOVE_SLAM,
+		MOVE_ROTATE_LEFT, MOVE_ROTATE_RIGHT, MOVE_HOLD,
+	}
+
+	// Random moves on random frames, played twice over
+	r := rand.New(rand.NewSource(0))
+	var frames [][]Movement
+	for i := 0; i < 5000; i++ {
+		var moves []Movement
+		if r.Intn(10) == 0 {
+			moves = append(moves, moveOptions[r.Intn(len(moveOptions))])
+		}
+		frames = append(frames, moves)
+	}
+
+	a, b := NewGame(3, 1), NewGame(3, 1)
+	for i, moves := range frames {
+		a.Step(moves)
+		b.Step(moves)
+
+		if !reflect.DeepEqual(a.Snap(), b.Snap()) {
+			t.Fatalf("Games went different ways on frame %v", i)
+		}
+	}
+
+	if a.Frame() != len(frames) && !a.IsGameover() {
+		t.Errorf("Expected %v frames, found %v", len(frames), a.Frame())
+	}
+}
+
+func TestStepFrameDurationLimits(t *testing.T) {
+	for _, frame := range []time.Duration{0, -time.Second} {
+		game := NewGame(0, 1, WithFrameDuration(frame))
+		if game.frame != DEFAULT_FRAME {
+			t.Errorf("%v: expected frames of %v, found %v", frame, DEFAULT_FRAME, game.frame)
+		}
+
+		// Stepping works like it would at the default rate
+		stepFrames(game, game.toFrames(linearGravity(1)))
+		if game.Frame() != game.toFrames(linearGravity(1)) {
+			t.Errorf("%v: expected to have stepped, found frame %v", frame, game.Frame())
+		}
+	}
+}
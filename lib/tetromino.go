@@ -1,7 +1,9 @@
 package lib
 
 import (
-	"math/rand"
+	"fmt"
+	"image/color"
+	"math"
 )
 
 type Shape int
@@ -27,6 +29,47 @@ type TetGrid struct {
 	size int
 }
 
+// Makes a grid out of a mask, read from the top left a row at a time.
+// The mask has to be square, so it's size is worked out from it's
+// length. Panics if it isn't, see TryNewTetGrid.
+func NewTetGrid(mask []bool) TetGrid {
+	grid, err := TryNewTetGrid(mask)
+	if err != nil {
+		panic(err)
+	}
+
+	return grid
+}
+
+// Like NewTetGrid, but returns ErrInvalidShape instead of panicking
+func TryNewTetGrid(mask []bool) (TetGrid, error) {
+	size := int(math.Sqrt(float64(len(mask))))
+	if size == 0 || size*size != len(mask) {
+		return TetGrid{}, fmt.Errorf("%w: a mask of %v tiles isn't square", ErrInvalidShape, len(mask))
+	}
+
+	return TetGrid{grid: append([]bool(nil), mask...), size: size}, nil
+}
+
+// Returns the width and height of the grid
+func (grid TetGrid) Size() int {
+	return grid.size
+}
+
+// Returns a copy of the grid with every tile turned into an n by n
+// block, like the pieces in the big mode of the TGM series
+func (grid TetGrid) Scale(n int) TetGrid {
+	size := grid.size * n
+	scaled := make([]bool, size*size)
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			scaled[y*size+x] = grid.grid[(y/n)*grid.size+x/n]
+		}
+	}
+
+	return TetGrid{grid: scaled, size: size}
+}
+
 var squareGrid = TetGrid{
 	grid: []bool{
 		true, true,
@@ -93,96 +136,185 @@ var lineGrid = TetGrid{
 // A tetromino is a given shape, that has some sort of mask which
 // determines it's direction. The mask is a pointer to a slice,
 // because we can do the rotations in advance, and just change to the
-// right mask when we rotate it
+// right mask when we rotate it. The set of masks we pick from belongs
+// to whichever rotation system the tetromino is being used with.
 type Tetromino struct {
 	mask        *[]bool
+	masks       []*[]bool
 	size        int
 	shape       Shape
 	rotationIdx int
 }
 
-// Pivots a square grid, returing the values of that grid but rotated.
+// Pivots a square grid to the left, returing the values of that grid
+// but rotated. The top row is made from the right column, read from
+// the top down, and so on.
 func pivot(grid []bool, size int) []bool {
-	switch size {
-	case 2:
-		return []bool{
-			grid[1], grid[3],
-			grid[0], grid[2],
-		}
-	case 3:
-		return []bool{
-			grid[2], grid[5], grid[8],
-			grid[1], grid[4], grid[7],
-			grid[0], grid[3], grid[6],
+	pivoted := make([]bool, len(grid))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			pivoted[y*size+x] = grid[x*size+size-1-y]
 		}
-	case 4:
-		return []bool{
-			grid[3], grid[7], grid[11], grid[15],
-			grid[2], grid[6], grid[10], grid[14],
-			grid[1], grid[5], grid[9], grid[13],
-			grid[0], grid[4], grid[8], grid[12],
-		}
-	default:
-		panic("Cannot rotate grid with provided size")
 	}
+
+	return pivoted
 }
 
+// The grid and the masks it pivots through for every shape, indexed
+// by shape. The seven tetrominos come first, followed by any shapes
+// that have been registered.
+var grids []TetGrid
 var rotations [][]*[]bool
 
+// The colors of registered shapes, indexed by shape. The tetrominos
+// don't have one, it's up to the renderer's palette.
+var shapeColors = map[Shape]color.RGBA{}
+
+// Builds the set of masks for a grid by pivoting it. The first mask
+// is the grid itself, and each mask after it is one more left
+// rotation.
+func pivotRotations(grid TetGrid) []*[]bool {
+	rotationSet := []*[]bool{}
+
+	r0 := grid.grid
+	for i := 0; i < 4; i++ {
+		// Each mask needs a variable of it's own, otherwise every
+		// pointer in the set would end up pointing to the last one
+		r := r0
+		rotationSet = append(rotationSet, &r)
+		r0 = pivot(r0, grid.size)
+	}
+
+	return rotationSet
+}
+
+// Builds a set of masks from masks that have been written out by
+// hand, in order of left rotations. Useful when a rotation system
+// doesn't simply pivot pieces around.
+func fixedRotations(masks ...[]bool) []*[]bool {
+	rotationSet := []*[]bool{}
+	for i := range masks {
+		rotationSet = append(rotationSet, &masks[i])
+	}
+
+	return rotationSet
+}
+
 func init() {
 	// Set the values for the grids, so rotations are just a matter of
 	// modifying an index for a lookup instead of actually doing a
 	// rotation
-	grids := []TetGrid{squareGrid, sGrid, zGrid, lGrid, tGrid, jGrid, lineGrid}
-	for _, grid := range grids {
-		rotationSet := []*[]bool{}
-
-		var r0 []bool = grid.grid
-		rotationSet = append(rotationSet, &r0)
-		for i := 0; i < 4; i++ {
-			r1 := pivot(r0, grid.size)
-			rotationSet = append(rotationSet, &r1)
-			r0 = r1
-		}
+	for _, grid := range []TetGrid{squareGrid, sGrid, zGrid, lGrid, tGrid, jGrid, lineGrid} {
+		grids = append(grids, grid)
+		rotations = append(rotations, pivotRotations(grid))
+	}
+}
 
-		rotations = append(rotations, rotationSet)
+// Adds a new piece, such as a pentomino or a scaled up tetromino, and
+// returns the shape to refer to it by. It's drawn in the given color,
+// and it's tiles get a TileColor of their own past C7. Pieces rotate
+// by pivoting their grid, and rotation systems without masks of their
+// own for it do the same. Shapes have to be registered before any
+// games start, since games don't lock the registry. Panics if the grid
+// has no tiles, see TryRegisterShape.
+func RegisterShape(grid TetGrid, c color.RGBA) Shape {
+	s, err := TryRegisterShape(grid, c)
+	if err != nil {
+		panic(err)
 	}
+
+	return s
+}
+
+// Like RegisterShape, but returns ErrInvalidShape instead of panicking
+func TryRegisterShape(grid TetGrid, c color.RGBA) (Shape, error) {
+	if grid.size == 0 || len(grid.grid) != grid.size*grid.size {
+		return 0, fmt.Errorf("%w: the grid has to be made with NewTetGrid", ErrInvalidShape)
+	}
+	if tileCount(grid) == 0 {
+		return 0, fmt.Errorf("%w: the grid has no tiles", ErrInvalidShape)
+	}
+
+	s := Shape(len(grids))
+	grids = append(grids, grid)
+	rotations = append(rotations, pivotRotations(grid))
+	shapeColors[s] = c
+
+	return s, nil
 }
 
+// Returns every shape, including the ones that have been registered
+func Shapes() []Shape {
+	all := make([]Shape, len(grids))
+	for i := range all {
+		all[i] = Shape(i)
+	}
+
+	return all
+}
+
+// Returns the grid a shape was made from. Panics if the shape doesn't
+// exist.
+func ShapeGrid(s Shape) TetGrid {
+	if err := checkShape(s); err != nil {
+		panic(err)
+	}
+
+	return NewTetGrid(grids[s].grid)
+}
+
+// Returns the color a registered shape was given. The tetrominos don't
+// have one, so false is returned for them.
+func ShapeColor(s Shape) (color.RGBA, bool) {
+	c, ok := shapeColors[s]
+	return c, ok
+}
+
+func checkShape(s Shape) error {
+	if s < 0 || int(s) >= len(grids) {
+		return fmt.Errorf("%w: %v", ErrInvalidShape, s)
+	}
+
+	return nil
+}
+
+// Panics if the shape doesn't exist, see TryNewTet
 func NewTet(s Shape) *Tetromino {
-	lookupSize := func() int {
-		switch s {
-		case TET_SQUARE:
-			return 2
-		case TET_J:
-			return 3
-		case TET_L:
-			return 3
-		case TET_T:
-			return 3
-		case TET_S:
-			return 3
-		case TET_Z:
-			return 3
-		case TET_LINE:
-			return 4
-		default:
-			panic("Invalid shape passed in")
-		}
+	tet, err := TryNewTet(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return tet
+}
+
+// Like NewTet, but returns ErrInvalidShape instead of panicking
+func TryNewTet(s Shape) (*Tetromino, error) {
+	if err := checkShape(s); err != nil {
+		return nil, err
 	}
 
 	return &Tetromino{
 		mask:  rotations[s][0],
-		size:  lookupSize(),
+		masks: rotations[s],
+		size:  grids[s].size,
 		shape: s,
-	}
+	}, nil
+}
+
+// Swaps the set of masks the tetromino rotates through, and points it
+// at the given rotation within that set
+func (tet *Tetromino) setMasks(masks []*[]bool, rotationIdx int) {
+	tet.masks = masks
+	tet.rotationIdx = rotationIdx
+	tet.mask = masks[rotationIdx]
 }
 
 func (tet *Tetromino) RotLeft() {
 	tet.rotationIdx++
 	// Reset to start if needed
 	tet.rotationIdx = tet.rotationIdx % 4
-	tet.mask = rotations[tet.shape][tet.rotationIdx]
+	tet.mask = tet.masks[tet.rotationIdx]
 }
 
 func (tet *Tetromino) RotRight() {
@@ -191,7 +323,17 @@ func (tet *Tetromino) RotRight() {
 	if tet.rotationIdx < 0 {
 		tet.rotationIdx = 3
 	}
-	tet.mask = rotations[tet.shape][tet.rotationIdx]
+	tet.mask = tet.masks[tet.rotationIdx]
+}
+
+func (tet *Tetromino) GetShape() Shape {
+	return tet.shape
+}
+
+// Returns the width and height of the tetromino's mask. Masks are
+// always square
+func (tet *Tetromino) GetSize() int {
+	return tet.size
 }
 
 // Returns a copy of the mask that the tetromino is pointing to
@@ -206,7 +348,7 @@ func (tet *Tetromino) GetMask() []bool {
 // Returns the mask that results from a left rotation
 func (tet *Tetromino) GetLeftRotationMask() []bool {
 	mask := make([]bool, len(*tet.mask))
-	copy(mask, *rotations[tet.shape][tet.rotationIdx+1%4])
+	copy(mask, *tet.masks[(tet.rotationIdx+1)%4])
 	return mask
 }
 
@@ -217,7 +359,7 @@ func (tet *Tetromino) GetRightRotationMask() []bool {
 	if i < 0 {
 		i = 3
 	}
-	copy(mask, *rotations[tet.shape][i])
+	copy(mask, *tet.masks[i])
 	return mask
 }
 
@@ -226,38 +368,3 @@ func ShapeToTC(s Shape) TileColor {
 	// corresponding empty shape
 	return TileColor(s + 1)
 }
-
-// Creates a read only channel that sends random shapes. We
-// paramaterize this with a seed
-func ShapeGenerator(seed int64) <-chan Shape {
-	r := rand.New(rand.NewSource(seed))
-
-	shapeC := make(chan Shape, 20)
-	var upcomingShapes []Shape
-	for _,s := range shapes {
-		upcomingShapes = append(upcomingShapes, s)
-	}
-
-	shuffle := func() {
-		r.Shuffle(len(upcomingShapes), func(i, j int) {
-			upcomingShapes[i], upcomingShapes[j] = upcomingShapes[j], upcomingShapes[i]
-		})
-	}
-
-	shuffle()
-
-	go func() {
-		var idx int
-		for {
-			if idx < 7 {
-				shapeC <- upcomingShapes[idx]
-				idx++
-			} else {
-				shuffle()
-				idx = 0
-			}
-		}
-	}()
-
-	return shapeC
-}
@@ -1,6 +1,9 @@
 package lib
 
 import (
+	"errors"
+	"image/color"
+	"math/rand"
 	"reflect"
 	"testing"
 	"time"
@@ -81,13 +84,13 @@ func TestShapeGeneration(t *testing.T) {
 	// the shapes. While random, it should be relatively equal. If
 	// it's too wonky, throw an error
 
-	shapeGen := ShapeGenerator(time.Now().UnixNano())
+	shapeGen := NewSevenBagRandomizer(time.Now().UnixNano())
 	counts := make(map[Shape]int)
 	const SAMPLES = 1000
 
 	var s Shape
 	for i := 0; i < SAMPLES; i++ {
-		s = <-shapeGen
+		s = shapeGen.Next()
 		counts[s]++
 	}
 
@@ -103,3 +106,154 @@ func TestShapeGeneration(t *testing.T) {
 		}
 	}
 }
+
+func TestPivot(t *testing.T) {
+	// The hand written tables pivot the same way
+	if found := pivot(tGrid.grid, 3); !reflect.DeepEqual(found, []bool{
+		false, true, false,
+		false, true, true,
+		false, true, false,
+	}) {
+		t.Errorf("T pivoted wrong: %v", found)
+	}
+
+	// Any size comes back around after four turns, and not before
+	for size := 1; size <= 6; size++ {
+		grid := make([]bool, size*size)
+		grid[size-1] = true
+
+		pivoted := grid
+		for i := 0; i < 4; i++ {
+			pivoted = pivot(pivoted, size)
+			if i < 3 && size > 1 && reflect.DeepEqual(pivoted, grid) {
+				t.Errorf("Size %v: back where it started after %v turns", size, i+1)
+			}
+		}
+		if !reflect.DeepEqual(pivoted, grid) {
+			t.Errorf("Size %v: not the same after four turns", size)
+		}
+	}
+}
+
+func TestTetGridScale(t *testing.T) {
+	big := squareGrid.Scale(2)
+	if big.Size() != 4 {
+		t.Fatalf("Expected a 4x4 grid, found %v", big.Size())
+	}
+	for _, filled := range big.grid {
+		if !filled {
+			t.Fatal("Expected the big square to be filled in")
+		}
+	}
+
+	bigT := ShapeGrid(TET_T).Scale(2)
+	var tiles int
+	for _, filled := range bigT.grid {
+		if filled {
+			tiles++
+		}
+	}
+	if tiles != 16 {
+		t.Errorf("Expected every tile of the T to be four, found %v tiles", tiles)
+	}
+}
+
+// Puts the shape registry back the way it was once the test is done,
+// so the shapes it registers don't leak into other tests
+func keepShapes(t *testing.T) {
+	n := len(grids)
+	colors := make(map[Shape]color.RGBA)
+	for s, c := range shapeColors {
+		colors[s] = c
+	}
+
+	t.Cleanup(func() {
+		grids = grids[:n]
+		rotations = rotations[:n]
+		shapeColors = colors
+	})
+}
+
+func TestRegisterShape(t *testing.T) {
+	keepShapes(t)
+	r := rand.New(rand.NewSource(1))
+
+	// The P pentomino
+	pink := color.RGBA{255, 105, 180, 255}
+	p := RegisterShape(NewTetGrid([]bool{
+		false, true, true,
+		false, true, true,
+		false, true, false,
+	}), pink)
+
+	if c, ok := ShapeColor(p); !ok || c != pink {
+		t.Errorf("Expected the shape to be pink, found %v", c)
+	}
+	if _, ok := ShapeColor(TET_T); ok {
+		t.Error("Expected the tetrominos to be left to the palette")
+	}
+	if all := Shapes(); all[len(all)-1] != p {
+		t.Errorf("Expected the shape to be listed, found %v", all)
+	}
+
+	tet := NewTet(p)
+	if tet.GetSize() != 3 || len(NewActiveTet(tet).ListPositions()) != 5 {
+		t.Fatalf("Expected a piece with five tiles")
+	}
+
+	// Every rotation system can spawn and turn it, and games can be
+	// played out with it
+	for name, rotation := range RotationSystems {
+		game := NewGame(0, 1,
+			WithRotationSystem(rotation),
+			WithRandomizer(func(seed int64) Randomizer {
+				return NewBagRandomizerOf(seed, p, TET_T)
+			}))
+
+		for i := 0; !game.IsGameover() && i < 10000; i++ {
+			game.Tick(Movement(r.Intn(int(MOVE_FORCE_DOWN))))
+			game.Tick(MOVE_FORCE_DOWN)
+		}
+
+		if !game.IsGameover() {
+			t.Errorf("%v: expected the game to end", name)
+		}
+	}
+}
+
+func TestRegisterShapeInvalid(t *testing.T) {
+	keepShapes(t)
+
+	registered := len(Shapes())
+	for name, grid := range map[string]TetGrid{
+		"zero":     {},
+		"no tiles": NewTetGrid(make([]bool, 4)),
+	} {
+		if _, err := TryRegisterShape(grid, color.RGBA{}); !errors.Is(err, ErrInvalidShape) {
+			t.Errorf("%v: expected ErrInvalidShape, found %v", name, err)
+		}
+	}
+
+	if len(Shapes()) != registered {
+		t.Error("Expected nothing to be registered")
+	}
+}
+
+func TestTryNewTet(t *testing.T) {
+	for _, s := range []Shape{-1, Shape(len(Shapes()))} {
+		if _, err := TryNewTet(s); !errors.Is(err, ErrInvalidShape) {
+			t.Errorf("Shape %v: expected ErrInvalidShape, found %v", s, err)
+		}
+	}
+
+	if tet, err := TryNewTet(TET_T); err != nil || tet.GetShape() != TET_T {
+		t.Errorf("Expected a T, found %v", err)
+	}
+
+	if _, err := TryNewTetGrid(make([]bool, 5)); !errors.Is(err, ErrInvalidShape) {
+		t.Errorf("Expected ErrInvalidShape for a mask that isn't square, found %v", err)
+	}
+	if _, err := TryNewTetGrid(nil); !errors.Is(err, ErrInvalidShape) {
+		t.Errorf("Expected ErrInvalidShape for an empty mask, found %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+package lib
+
+// The kinds of T-spin a tetromino can be locked with
+type TSpin int
+
+const (
+	NO_TSPIN TSpin = iota
+	TSPIN_MINI
+	TSPIN
+)
+
+// Works out whether the active tetromino would be locked as a T-spin,
+// using the three corner rule. The tetromino has to be a T, and the
+// last thing that happened to it has to be a rotation. If three of the
+// four corners around it's center are filled it's a T-spin, and if
+// either of the two corners it's pointing at are open it's only a mini.
+// A mini is upgraded when it got there with the long SRS kick.
+func (ctl *BoardController) TSpin() TSpin {
+	if ctl.tet.shape != TET_T || !ctl.lastRotated {
+		return NO_TSPIN
+	}
+
+	center, nub := tCenter(ctl.tet.ListPositions())
+
+	var front, back int
+	for _, dx := range []int{-1, 1} {
+		for _, dy := range []int{-1, 1} {
+			corner := Position{center.x + dx, center.y + dy}
+			if !ctl.blocked(corner) {
+				continue
+			}
+
+			// The front corners are the ones on the same side as the
+			// nub. The nub is a single step away from the center, so
+			// the corner only needs to agree with it on one axis
+			if dx == nub.x-center.x || dy == nub.y-center.y {
+				front++
+			} else {
+				back++
+			}
+		}
+	}
+
+	switch {
+	case front+back < 3:
+		return NO_TSPIN
+	case front == 2 || isLongKick(ctl.lastKick):
+		return TSPIN
+	default:
+		return TSPIN_MINI
+	}
+}
+
+// Returns true if a position is outside of the board, or has a tile on
+// it. Walls and the floor count as filled corners for a T-spin
+func (ctl *BoardController) blocked(p Position) bool {
+	return ctl.board.Blocked(p.x, p.y)
+}
+
+// Finds the center tile of a T, and the tile that sticks out of it's
+// flat side. Done with the tiles themselves rather than the mask, since
+// every rotation system lays out it's boxes differently.
+func tCenter(ps []Position) (center Position, nub Position) {
+	has := make(map[Position]bool, len(ps))
+	for _, p := range ps {
+		has[p] = true
+	}
+
+	steps := []Position{{-1, 0}, {1, 0}, {0, -1}, {0, 1}}
+	for _, p := range ps {
+		var neighbours []Position
+		for _, step := range steps {
+			if n := (Position{p.x + step.x, p.y + step.y}); has[n] {
+				neighbours = append(neighbours, n)
+			}
+		}
+
+		if len(neighbours) != 3 {
+			continue
+		}
+
+		// The nub is the only neighbour without one opposite it
+		for _, n := range neighbours {
+			if !has[Position{2*p.x - n.x, 2*p.y - n.y}] {
+				return p, n
+			}
+		}
+	}
+
+	return Position{}, Position{}
+}
+
+// The last SRS kick for the J, L, S, T and Z pieces moves them one tile
+// across and two tiles up or down. Landing a T with it always counts
+// as a full T-spin.
+func isLongKick(kick Position) bool {
+	abs := func(n int) int {
+		if n < 0 {
+			return -n
+		}
+		return n
+	}
+
+	return abs(kick.x) == 1 && abs(kick.y) == 2
+}
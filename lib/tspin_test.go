@@ -0,0 +1,95 @@
+package lib
+
+import (
+	"testing"
+)
+
+// Builds a T-spin double slot along the bottom of the board, with an
+// overhang on the left side of it
+func tSlotBoard() *Board {
+	board := &Board{}
+	for x := 0; x < BOARD_WIDTH; x++ {
+		if x != 4 {
+			board.SetTile(C1, x, 0)
+		}
+		if x < 3 || x > 5 {
+			board.SetTile(C1, x, 1)
+		}
+	}
+	board.SetTile(C1, 3, 2)
+
+	return board
+}
+
+func TestTSpinDouble(t *testing.T) {
+	board := tSlotBoard()
+
+	// Pointing left, in the slot. Rotating left points it down into the
+	// bottom of the slot without needing a kick
+	ctl := &BoardController{board: board, rotation: SRSRotation{}, rules: ClassicRuleset()}
+	ctl.tet = ActiveTetromino{tetInState(TET_T, SRS_L), Position{3, 2}}
+
+	if result := ctl.Tick(MOVE_ROTATE_LEFT, nil); !result.Moved {
+		t.Fatal("T couldn't rotate into the slot")
+	}
+
+	if tSpin := ctl.TSpin(); tSpin != TSPIN {
+		t.Errorf("Expected a T-spin, found %v", tSpin)
+	}
+
+	result := ctl.Tick(MOVE_SLAM, NewTet(TET_SQUARE))
+	if result.TSpin != TSPIN || result.Lines != 2 || result.Dropped != 0 {
+		t.Errorf("Expected a T-spin double without a drop, found %+v", result)
+	}
+
+	if result.PerfectClear {
+		t.Error("The overhang is still on the board, this isn't a perfect clear")
+	}
+}
+
+func TestTSpinNeedsRotation(t *testing.T) {
+	ctl := &BoardController{board: tSlotBoard(), rotation: SRSRotation{}, rules: ClassicRuleset()}
+	ctl.tet = ActiveTetromino{tetInState(TET_T, SRS_2), Position{3, 2}}
+
+	// Already in the slot, but it never rotated
+	if tSpin := ctl.TSpin(); tSpin != NO_TSPIN {
+		t.Errorf("Expected no T-spin without a rotation, found %v", tSpin)
+	}
+
+	// Falling into it after a rotation doesn't count either
+	ctl.tet.y++
+	ctl.lastRotated = true
+	if !ctl.Move(DOWN) {
+		t.Fatal("T couldn't fall into the slot")
+	}
+	if tSpin := ctl.TSpin(); tSpin != NO_TSPIN {
+		t.Errorf("Expected no T-spin after moving, found %v", tSpin)
+	}
+}
+
+func TestTSpinMini(t *testing.T) {
+	// Pointing right against the left wall. The wall fills both back
+	// corners, but only one of the front corners is filled
+	board := &Board{}
+	board.SetTile(C1, 1, 0)
+
+	ctl := &BoardController{board: board, rotation: SRSRotation{}, rules: ClassicRuleset()}
+	ctl.tet = ActiveTetromino{tetInState(TET_T, SRS_R), Position{-1, 2}}
+	ctl.lastRotated = true
+
+	if tSpin := ctl.TSpin(); tSpin != TSPIN_MINI {
+		t.Errorf("Expected a T-spin mini, found %v", tSpin)
+	}
+
+	// Getting there with the long kick upgrades it
+	ctl.lastKick = Position{-1, 2}
+	if tSpin := ctl.TSpin(); tSpin != TSPIN {
+		t.Errorf("Expected the long kick to upgrade to a T-spin, found %v", tSpin)
+	}
+
+	// Only a T can spin
+	ctl.tet = ActiveTetromino{tetInState(TET_L, SRS_R), Position{-1, 2}}
+	if tSpin := ctl.TSpin(); tSpin != NO_TSPIN {
+		t.Errorf("Expected no T-spin for an L, found %v", tSpin)
+	}
+}
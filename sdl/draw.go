@@ -3,12 +3,15 @@ package sdl
 import (
 	gosdl "github.com/veandco/go-sdl2/sdl"
 
+	"errors"
+	"fmt"
 	"image/color"
 
 	"tetris/lib"
 )
 
-// A palette must have exactly 7 colors
+// A palette must have exactly 7 colors, one for each tetromino.
+// Registered shapes bring colors of their own.
 type Palette [7]color.RGBA
 
 func LookupColor(tc lib.TileColor, p Palette) color.RGBA {
@@ -19,7 +22,25 @@ func LookupColor(tc lib.TileColor, p Palette) color.RGBA {
 
 	// Since the empty color has no actual color, shift every number
 	// down by one
-	return p[int(tc)-1]
+	idx := int(tc) - 1
+	if idx < len(p) {
+		return p[idx]
+	}
+
+	c, _ := lib.ShapeColor(lib.Shape(idx))
+	return c
+}
+
+// Blends a color halfway into the empty tile color, so it looks
+// translucent when drawn over an empty part of the board
+func Translucent(c color.RGBA) color.RGBA {
+	empty := LookupColor(lib.EMPTY, Palette{})
+	return color.RGBA{
+		uint8((int(c.R) + int(empty.R)) / 2),
+		uint8((int(c.G) + int(empty.G)) / 2),
+		uint8((int(c.B) + int(empty.B)) / 2),
+		255,
+	}
 }
 
 // Helper function for creating surfaces
@@ -64,6 +85,17 @@ func FillRect(surf *gosdl.Surface, rect gosdl.Rect, color color.RGBA) {
 	}
 }
 
+// Draws just the border of a rectangle, with lines of the given
+// thickness drawn inside of the rectangle's edges
+func OutlineRect(surf *gosdl.Surface, rect gosdl.Rect, thickness int, color color.RGBA) {
+	x, y, w, h := int(rect.X), int(rect.Y), int(rect.W), int(rect.H)
+
+	FillRect(surf, Rect(x, y, w, thickness), color)
+	FillRect(surf, Rect(x, y+h-thickness, w, thickness), color)
+	FillRect(surf, Rect(x, y, thickness, h), color)
+	FillRect(surf, Rect(x+w-thickness, y, thickness, h), color)
+}
+
 // Clears the specified surface, by setting everything to the
 // transparent color
 func ClearSurface(surf *gosdl.Surface) {
@@ -84,14 +116,63 @@ type BoardComponent struct {
 	surf    *gosdl.Surface
 	w       int
 	h       int
+	// Where the current tetromino will land, and it's color
+	ghost   []lib.Position
+	ghostTC lib.TileColor
+	// Full rows that are about to be cleared
+	clearing []int
 }
 
 const W_MIN = 50
 const H_MIN = 100
 
-func NewBoardComponent(initBoard lib.Board, p Palette, w int, h int) *BoardComponent {
+// Returned when a component is asked to be smaller than W_MIN by H_MIN
+var ErrTooSmall = errors.New("component too small")
+
+func checkSize(w, h int) error {
 	if w < W_MIN || h < H_MIN {
-		panic("Cannot create board component that small. Minimum supported size is 50x100")
+		return fmt.Errorf("%w: %vx%v, the minimum supported size is %vx%v", ErrTooSmall, w, h, W_MIN, H_MIN)
+	}
+
+	return nil
+}
+
+// Rows that are about to be cleared are drawn in this color
+var CLEARING_COLOR = color.RGBA{255, 255, 255, 255}
+
+// Works out how big each tile of a board with the given number of
+// columns and visible rows should be to fit inside the given width
+// and height, and the offsets needed to center the board within that
+// space
+func boardLayout(w, h, cols, rows int) (rectSize, xOff, yOff int) {
+	rectSize = w / cols
+	if h/rows < rectSize {
+		rectSize = h / rows
+	}
+
+	realW := rectSize * cols
+	realH := rectSize * rows
+
+	xOff = (w - realW) / 2
+	yOff = (h - realH) / 2
+
+	return rectSize, xOff, yOff
+}
+
+// Panics if the component would be too small, see TryNewBoardComponent
+func NewBoardComponent(initBoard lib.Board, p Palette, w int, h int) *BoardComponent {
+	bc, err := TryNewBoardComponent(initBoard, p, w, h)
+	if err != nil {
+		panic(err)
+	}
+
+	return bc
+}
+
+// Like NewBoardComponent, but returns ErrTooSmall instead of panicking
+func TryNewBoardComponent(initBoard lib.Board, p Palette, w int, h int) (*BoardComponent, error) {
+	if err := checkSize(w, h); err != nil {
+		return nil, err
 	}
 
 	return &BoardComponent{
@@ -100,7 +181,7 @@ func NewBoardComponent(initBoard lib.Board, p Palette, w int, h int) *BoardCompo
 		surf:    NewSurface(w, h),
 		w:       w,
 		h:       h,
-	}
+	}, nil
 }
 
 func (bc *BoardComponent) GetSurface() *gosdl.Surface {
@@ -111,79 +192,127 @@ func (bc *BoardComponent) Draw() {
 	// Update the surface with the contents of the board
 
 	// Figure out what the size of each rect should be
-	var rectSize int
-	if bc.h/bc.w >= 2 {
-		rectSize = bc.w / 10
-	} else {
-		rectSize = bc.h / 20
-	}
-
-	realW := rectSize * 10
-	realH := rectSize * 20
+	cols, rows := bc.board.Width(), bc.board.Visible()
+	rectSize, xOff, yOff := boardLayout(bc.w, bc.h, cols, rows)
 
-	xOff := (bc.w - realW) / 2
-	yOff := (bc.h - realH) / 2
-
-	// Draw tiles (0,0) -> (0,19), by drawing rectangles
+	// Draw the visible tiles, by drawing rectangles
 	var rect gosdl.Rect
 	var tc lib.TileColor
-	for y := 0; y < 20; y++ {
-		for x := 0; x < 10; x++ {
+	for y := 0; y < rows; y++ {
+		for x := 0; x < cols; x++ {
 			tc = bc.board.GetTile(x, y)
-			rect = Rect(xOff+x*rectSize, yOff+(20-y-1)*rectSize, rectSize, rectSize)
+			rect = Rect(xOff+x*rectSize, yOff+(rows-y-1)*rectSize, rectSize, rectSize)
 			FillRect(bc.surf, rect, LookupColor(tc, bc.palette))
 		}
 	}
+
+	// Light up rows that are being cleared, so it's clear where they
+	// went
+	for _, y := range bc.clearing {
+		if y >= rows {
+			continue
+		}
+
+		rect = Rect(xOff, yOff+(rows-y-1)*rectSize, rectSize*cols, rectSize)
+		FillRect(bc.surf, rect, CLEARING_COLOR)
+	}
+
+	// Outline where the current tetromino will land. Skip any tiles
+	// that are already filled, which happens once it's landed
+	thickness := rectSize / 10
+	if thickness < 1 {
+		thickness = 1
+	}
+
+	ghostColor := Translucent(LookupColor(bc.ghostTC, bc.palette))
+	for _, p := range bc.ghost {
+		x, y := p.GetPos()
+		if y >= rows || !bc.board.IsEmpty(x, y) {
+			continue
+		}
+
+		rect = Rect(xOff+x*rectSize, yOff+(rows-y-1)*rectSize, rectSize, rectSize)
+		OutlineRect(bc.surf, rect, thickness, ghostColor)
+	}
 }
 
 func (bc *BoardComponent) Update(snap lib.GameSnapshot) {
-	b := snap.Board
-	if b != bc.board {
+	// The snapshot's board only has the locked tiles, the current
+	// tetromino needs to be drawn on top of them
+	b := snap.View()
+	if !b.Equal(&bc.board) || !samePositions(snap.Ghost, bc.ghost) || !sameRows(snap.Clearing, bc.clearing) {
 		ClearSurface(bc.surf)
 		bc.board = b
+		bc.ghost = snap.Ghost
+		bc.ghostTC = lib.ShapeToTC(snap.CurrentTet.GetShape())
+		bc.clearing = snap.Clearing
 		bc.Draw()
 	}
 }
 
-// Creates a grid that is meant to be directly overlayed on top of a
-// board, so it's more apparent how the tetrominos are layed out. This
-// is a static component, so the surface is returned directly
-func MakeGrid(w, h int) *gosdl.Surface {
-	if w < W_MIN || h < H_MIN {
-		panic("Cannot create a grid of provided size. Minumum supported size is 50x100")
+// Returns true if both lists contain the same rows in the same order
+func sameRows(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
 	}
 
-	surf := NewSurface(w, h)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Returns true if both lists contain the same positions in the same
+// order
+func samePositions(a, b []lib.Position) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
 
-	var lineSize int
-	if h/w >= 2 {
-		lineSize = w / 10
-	} else {
-		lineSize = h / 20
+// Creates a grid that is meant to be directly overlayed on top of a
+// board with the given number of columns and visible rows, so it's
+// more apparent how the tetrominos are layed out. This is a static
+// component, so the surface is returned directly
+func MakeGrid(w, h, cols, rows int) *gosdl.Surface {
+	if err := checkSize(w, h); err != nil {
+		panic(err)
 	}
 
-	realW := lineSize * 10
-	realH := lineSize * 20
+	surf := NewSurface(w, h)
+
+	lineSize, xOff, yOff := boardLayout(w, h, cols, rows)
 
-	xOff := (w - realW) / 2
-	yOff := (h - realH) / 2
+	realW := lineSize * cols
+	realH := lineSize * rows
 
 	LINE_COLOR := color.RGBA{200, 200, 200, 200}
 
 	var line gosdl.Rect
 	// Draw horizonal lines
-	for y := 0; y < 20; y++ {
+	for y := 0; y < rows; y++ {
 		line = Rect(xOff, yOff+y*lineSize, realW, 1)
 		FillRect(surf, line, LINE_COLOR)
 	}
-	line = Rect(xOff, yOff+20*lineSize-1, realW, 1)
+	line = Rect(xOff, yOff+rows*lineSize-1, realW, 1)
 	FillRect(surf, line, LINE_COLOR)
 	// Draw vertical lines
-	for x := 0; x < 10; x++ {
+	for x := 0; x < cols; x++ {
 		line = Rect(xOff+x*lineSize, yOff, 1, realH)
 		FillRect(surf, line, LINE_COLOR)
 	}
-	line = Rect(xOff+10*lineSize-1, yOff, 1, realH)
+	line = Rect(xOff+cols*lineSize-1, yOff, 1, realH)
 	FillRect(surf, line, LINE_COLOR)
 
 	return surf
@@ -1,12 +1,26 @@
 package sdl
 
 import (
+	"log"
+	"sync"
+	"time"
+
 	gosdl "github.com/veandco/go-sdl2/sdl"
 	"tetris/lib"
 )
 
 type EventMgr struct {
 	C       chan lib.Movement
+	// Closed once the window is closed
+	Quit     chan struct{}
+	quitOnce sync.Once
+	// Closed by Stop, once nothing is reading from C anymore
+	done     chan struct{}
+	stopOnce sync.Once
+	// Turns key presses and releases into movements, repeating held
+	// keys. Shared with whoever sets the gravity, so it's locked
+	mu    sync.Mutex
+	input *lib.InputHandler
 }
 
 var defaultInputMap map[gosdl.Keycode]lib.Movement
@@ -14,13 +28,15 @@ var debugInputMap map[gosdl.Keycode]lib.Movement
 
 func init() {
 	defaultInputMap = map[gosdl.Keycode]lib.Movement{
-		gosdl.K_DOWN:  lib.MOVE_DOWN,
-		gosdl.K_UP:    lib.MOVE_ROTATE_LEFT,
-		gosdl.K_LEFT:  lib.MOVE_LEFT,
-		gosdl.K_RIGHT: lib.MOVE_RIGHT,
-		gosdl.K_a:     lib.MOVE_ROTATE_LEFT,
-		gosdl.K_d:     lib.MOVE_ROTATE_RIGHT,
-		gosdl.K_SPACE: lib.MOVE_SLAM,
+		gosdl.K_DOWN:   lib.MOVE_DOWN,
+		gosdl.K_UP:     lib.MOVE_ROTATE_LEFT,
+		gosdl.K_LEFT:   lib.MOVE_LEFT,
+		gosdl.K_RIGHT:  lib.MOVE_RIGHT,
+		gosdl.K_a:      lib.MOVE_ROTATE_LEFT,
+		gosdl.K_d:      lib.MOVE_ROTATE_RIGHT,
+		gosdl.K_SPACE:  lib.MOVE_SLAM,
+		gosdl.K_c:      lib.MOVE_HOLD,
+		gosdl.K_LSHIFT: lib.MOVE_HOLD,
 	}
 
 	debugInputMap = map[gosdl.Keycode]lib.Movement{
@@ -32,36 +48,121 @@ func init() {
 		gosdl.K_d:     lib.MOVE_ROTATE_RIGHT,
 		gosdl.K_SPACE: lib.MOVE_SLAM,
 		gosdl.K_s:     lib.MOVE_FORCE_DOWN,
+		gosdl.K_c:     lib.MOVE_HOLD,
 	}
 }
 
-func NewEventMgr(inC chan gosdl.Event, debug bool) *EventMgr {
+func NewEventMgr(inC chan gosdl.Event, debug bool, config lib.InputConfig) *EventMgr {
 	mapping := defaultInputMap
 	if debug {
 		mapping = debugInputMap
 	}
 
-	outC := make(chan lib.Movement)
+	mgr := &EventMgr{
+		C:     make(chan lib.Movement),
+		Quit:  make(chan struct{}),
+		done:  make(chan struct{}),
+		input: lib.NewInputHandler(config),
+	}
 
-	// Process all events
+	// Process all events. Held keys are repeated by the input handler,
+	// which is moved forward once a frame
 	go func() {
+		ticker := time.NewTicker(lib.DEFAULT_FRAME)
+		last := time.Now()
+
 		var code gosdl.Keycode
 		var kevt *gosdl.KeyboardEvent
-		for evt := range inC {
-			if evt.GetType() == gosdl.KEYDOWN {
+		for {
+			var moves []lib.Movement
+
+			select {
+			case evt, ok := <-inC:
+				if !ok {
+					ticker.Stop()
+					return
+				}
+
+				evtType := evt.GetType()
+				if evtType == gosdl.QUIT {
+					mgr.quitOnce.Do(func() {
+						log.Print("Quit event received, stopping the game")
+						close(mgr.Quit)
+					})
+					continue
+				}
+				if evtType != gosdl.KEYDOWN && evtType != gosdl.KEYUP {
+					continue
+				}
+
 				// Must be a keyboard event, cast it, and then get
 				// it's keycode
 				kevt = evt.(*gosdl.KeyboardEvent)
 				code = kevt.Keysym.Sym
-				outMove, ok := mapping[code]
-				if ok {
-					outC <- outMove
+				button, ok := mapping[code]
+				if !ok {
+					// Ignore any events that don't fit the mapping
+					continue
 				}
 
+				mgr.mu.Lock()
+				if evtType == gosdl.KEYUP {
+					mgr.input.Release(button)
+				} else if kevt.Repeat == 0 {
+					// Repeats from the OS are ignored, the input
+					// handler does it's own
+					moves = mgr.input.Press(button)
+				}
+				mgr.mu.Unlock()
+			case now := <-ticker.C:
+				mgr.mu.Lock()
+				moves = mgr.input.Advance(now.Sub(last))
+				mgr.mu.Unlock()
+				last = now
+			}
+
+			// Once the game is done with them, the moves are
+			// dropped so events keep being read
+			for _, move := range moves {
+				select {
+				case mgr.C <- move:
+				case <-mgr.done:
+				}
 			}
-			// Ignore any events that don't fit the mapping
 		}
 	}()
 
-	return &EventMgr{outC}
+	return mgr
+}
+
+// Stops sending movements on C, for once the game isn't being played
+// anymore. Safe to call more than once.
+func (mgr *EventMgr) Stop() {
+	mgr.stopOnce.Do(func() {
+		close(mgr.done)
+	})
+}
+
+// Returns true if the key for a movement is being held down. Games use
+// this to rotate and hold tetrominos as they come in
+func (mgr *EventMgr) Held(button lib.Movement) bool {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+	return mgr.input.Held(button)
+}
+
+// Tells the input handler a new tetromino has come into play, so a
+// held direction with an instant repeat shifts it as well
+func (mgr *EventMgr) NewPiece() {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+	mgr.input.NewPiece()
+}
+
+// Tells the input handler how fast tetrominos are falling, so a held
+// soft drop can be faster still
+func (mgr *EventMgr) SetGravity(gravity time.Duration) {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+	mgr.input.SetGravity(gravity)
 }
@@ -3,8 +3,7 @@ package sdl
 import (
 	gosdl "github.com/veandco/go-sdl2/sdl"
 
-	"log"
-	"os"
+	"tetris/lib"
 )
 
 // This is needed for properly converting colors. For some reason we
@@ -13,9 +12,10 @@ var pxFormat *gosdl.PixelFormat
 
 // Initializes SDL and starts everything related to it. This must be
 // called before other managers are initialized, since they rely on
-// the functionality here. Also listens for the quit event and exits
-// if we attempt to close the window
-func Init(xres, yres int, debug bool) (*EventMgr, *DisplayMgr) {
+// the functionality here. Closing the window closes the event
+// manager's Quit channel, so the game can be stopped and saved. Held
+// keys repeat as the input config says
+func Init(xres, yres int, debug bool, input lib.InputConfig) (*EventMgr, *DisplayMgr) {
 	if err := gosdl.Init(gosdl.INIT_EVERYTHING); err != nil {
 		panic(err)
 	}
@@ -24,15 +24,7 @@ func Init(xres, yres int, debug bool) (*EventMgr, *DisplayMgr) {
 
 	go func() {
 		for event := gosdl.WaitEvent(); true; event = gosdl.WaitEvent() {
-			switch event.(type) {
-			case *gosdl.QuitEvent:
-				log.Print("Quit Event Received, exiting...")
-				gosdl.Quit()
-				os.Exit(0)
-
-			default:
-				eventChan <- event
-			}
+			eventChan <- event
 		}
 	}()
 
@@ -52,5 +44,10 @@ func Init(xres, yres int, debug bool) (*EventMgr, *DisplayMgr) {
 		}
 	}
 
-	return NewEventMgr(eventChan, debug), NewDisplayMgr("Tetris", xres, yres)
+	return NewEventMgr(eventChan, debug, input), NewDisplayMgr("Tetris", xres, yres)
+}
+
+// Shuts SDL down, once everything is done with it
+func Quit() {
+	gosdl.Quit()
 }
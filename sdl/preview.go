@@ -0,0 +1,139 @@
+package sdl
+
+import (
+	gosdl "github.com/veandco/go-sdl2/sdl"
+
+	"image/color"
+
+	"tetris/lib"
+)
+
+// Draws the upcoming tetrominos in a column beside the board. It
+// shares the same sized surface as the board component, and only
+// ever draws in the space to the right of the board.
+type PreviewComponent struct {
+	preview []lib.Tetromino
+	// The size of the board it's drawn beside
+	cols    int
+	rows    int
+	palette Palette
+	surf    *gosdl.Surface
+	w       int
+	h       int
+}
+
+// Each tetromino in the preview gets a slot this many tiles tall,
+// which leaves a gap between even the tallest pieces
+const PREVIEW_SLOT = 5
+
+// Panics if the component would be too small, see
+// TryNewPreviewComponent
+func NewPreviewComponent(initSnap lib.GameSnapshot, p Palette, w int, h int) *PreviewComponent {
+	pc, err := TryNewPreviewComponent(initSnap, p, w, h)
+	if err != nil {
+		panic(err)
+	}
+
+	return pc
+}
+
+// Like NewPreviewComponent, but returns ErrTooSmall instead of
+// panicking. The board and preview are laid out from the snapshot, so
+// the preview is drawn right away and lines up with the board from
+// the start.
+func TryNewPreviewComponent(initSnap lib.GameSnapshot, p Palette, w int, h int) (*PreviewComponent, error) {
+	if err := checkSize(w, h); err != nil {
+		return nil, err
+	}
+
+	pc := &PreviewComponent{
+		preview: initSnap.Preview,
+		cols:    initSnap.Board.Width(),
+		rows:    initSnap.Board.Visible(),
+		palette: p,
+		surf:    NewSurface(w, h),
+		w:       w,
+		h:       h,
+	}
+	pc.Draw()
+
+	return pc, nil
+}
+
+func (pc *PreviewComponent) GetSurface() *gosdl.Surface {
+	return pc.surf
+}
+
+func (pc *PreviewComponent) Draw() {
+	boardRectSize, xOff, yOff := boardLayout(pc.w, pc.h, pc.cols, pc.rows)
+
+	right := xOff + boardRectSize*pc.cols
+	if right >= pc.w {
+		// The board takes up the whole width, there's nowhere to draw
+		return
+	}
+
+	// Clear the margin with the same fill as ClearSurface, leaving the
+	// rest of the surface alone so the board underneath shows through
+	FillRect(pc.surf, Rect(right, 0, pc.w-right, pc.h), color.RGBA{255, 255, 255, 255})
+
+	// Previews are drawn at half the size of the board's tiles, in
+	// the margin to the right of the board. They shrink to fit when
+	// the margin is too narrow for a slot, or too short for them all.
+	rectSize := boardRectSize / 2
+	if fit := (pc.w - right) / PREVIEW_SLOT; fit < rectSize {
+		rectSize = fit
+	}
+	if n := len(pc.preview); n > 0 {
+		if fit := (pc.h - yOff) / (n * PREVIEW_SLOT); fit < rectSize {
+			rectSize = fit
+		}
+	}
+	if rectSize <= 0 {
+		return
+	}
+	left := right + rectSize
+
+	var rect gosdl.Rect
+	for i, tet := range pc.preview {
+		top := yOff + i*PREVIEW_SLOT*rectSize
+		size := tet.GetSize()
+		mask := tet.GetMask()
+		tileColor := LookupColor(lib.ShapeToTC(tet.GetShape()), pc.palette)
+
+		for dy := 0; dy < size; dy++ {
+			for dx := 0; dx < size; dx++ {
+				if mask[dy*size+dx] {
+					rect = Rect(left+dx*rectSize, top+dy*rectSize, rectSize, rectSize)
+					FillRect(pc.surf, rect, tileColor)
+				}
+			}
+		}
+	}
+}
+
+func (pc *PreviewComponent) Update(snap lib.GameSnapshot) {
+	cols, rows := snap.Board.Width(), snap.Board.Visible()
+	if !pc.changed(snap.Preview) && cols == pc.cols && rows == pc.rows {
+		return
+	}
+
+	pc.preview = snap.Preview
+	pc.cols, pc.rows = cols, rows
+	pc.Draw()
+}
+
+// Returns true if the preview differs from the one last drawn
+func (pc *PreviewComponent) changed(preview []lib.Tetromino) bool {
+	if len(preview) != len(pc.preview) {
+		return true
+	}
+
+	for i := range preview {
+		if preview[i].GetShape() != pc.preview[i].GetShape() {
+			return true
+		}
+	}
+
+	return false
+}